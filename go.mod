@@ -3,13 +3,16 @@ module github.com/mayadata-io/cli-utils
 go 1.14
 
 require (
+	github.com/Azure/go-autorest v14.2.0+incompatible // indirect
 	github.com/argoproj/argo v0.0.0-20200806220847-5759a0e198d3
 	github.com/go-resty/resty/v2 v2.3.0
 	github.com/imdario/mergo v0.3.11 // indirect
+	github.com/robfig/cron/v3 v3.0.1
 	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
 	gopkg.in/yaml.v2 v2.3.0
 	k8s.io/api v0.19.2
 	k8s.io/apimachinery v0.19.3
 	k8s.io/client-go v0.19.2
 	k8s.io/utils v0.0.0-20200912215256-4140de9c8800 // indirect
-)
\ No newline at end of file
+	sigs.k8s.io/yaml v1.2.0
+)
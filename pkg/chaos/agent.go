@@ -1,12 +1,13 @@
 package chaos
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	util "github.com/mayadata-io/cli-utils/pkg/common"
 	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
-
-	resty "github.com/go-resty/resty/v2"
+	"github.com/mayadata-io/cli-utils/pkg/common/prompt"
 	"github.com/mayadata-io/cli-utils/pkg/constants"
 )
 
@@ -31,146 +32,301 @@ type UserAgentReg struct {
 }
 
 // GetAgentDetails take details of agent as input
-func GetAgentDetails(pid string, t util.Token, cred util.Credentials) util.Agent {
-	var newAgent util.Agent
-	// Get agent name as input
-	fmt.Println("\n🔗 Enter the details of the agent ----")
-	fmt.Print("🤷 Agent Name: ")
-	newAgent.AgentName = util.Scanner()
-	for newAgent.AgentName == "" {
-		fmt.Println("⛔ Agent name cannot be empty. Please enter a valid name.")
-		fmt.Print("🤷 Agent Name: ")
-		newAgent.AgentName = util.Scanner()
-	}
-	i := 0
-	// Check if agent with the given name already exists
-	for AgentExists(pid, newAgent.AgentName, t, cred) {
-		// Print agent list if existing agent name is entered twice
-		if i < 1 {
-			fmt.Println("🚫 Agent with the given name already exists.\n❗ Please enter a different name.")
-			fmt.Print("🤷 Agent Name: ")
-			newAgent.AgentName = util.Scanner()
-			i++
-		} else {
-			fmt.Println("🚫 Agent with the given name already exists.")
-			GetAgentList(pid, t, cred)
-			fmt.Println("❗ Please enter a different name.")
-			fmt.Print("\n🤷 Agent Name: ")
-			newAgent.AgentName = util.Scanner()
+func GetAgentDetails(pid string, s *util.Session) (util.Agent, error) {
+	return FillAgentDetails(util.Agent{ProjectId: pid}, s)
+}
+
+// FillAgentDetails takes the details of agent which are
+// left empty as input and validates the ones already set
+func FillAgentDetails(newAgent util.Agent, s *util.Session) (util.Agent, error) {
+	pid := newAgent.ProjectId
+	if newAgent.AgentName != "" {
+		// Agent name is given, it can't be taken as input again
+		exists, err := AgentExists(pid, newAgent.AgentName, s)
+		if err != nil {
+			return util.Agent{}, err
+		}
+		if exists {
+			return util.Agent{}, fmt.Errorf("%w: %s", ErrAgentExists, newAgent.AgentName)
+		}
+	} else {
+		// Get agent name as input
+		p := prompt.Default()
+		p.Println("\n🔗 Enter the details of the agent ----")
+		var err error
+		if newAgent.AgentName, err = agentNameInput(p); err != nil {
+			return util.Agent{}, err
+		}
+		// Check if agent with the given name already exists
+		for i := 0; ; i++ {
+			exists, err := AgentExists(pid, newAgent.AgentName, s)
+			if err != nil {
+				return util.Agent{}, err
+			}
+			if !exists {
+				break
+			}
+			// Print agent list if existing agent name is entered twice
+			if i < 1 {
+				p.Println("🚫 Agent with the given name already exists.\n❗ Please enter a different name.")
+			} else {
+				p.Println("🚫 Agent with the given name already exists.")
+				if err := GetAgentList(pid, s); err != nil {
+					return util.Agent{}, err
+				}
+				p.Println("❗ Please enter a different name.")
+			}
+			if newAgent.AgentName, err = agentNameInput(p); err != nil {
+				return util.Agent{}, err
+			}
 		}
 	}
 	// Get agent description as input
-	fmt.Print("📘 Agent Description: ")
-	newAgent.Description = util.Scanner()
+	if newAgent.Description == "" {
+		var err error
+		if newAgent.Description, err = prompt.Default().Input("📘 Agent Description", ""); err != nil {
+			return util.Agent{}, err
+		}
+	}
 	// Get platform name as input
-	newAgent.PlatformName = util.GetPlatformName()
+	if newAgent.PlatformName == "" {
+		var err error
+		if newAgent.PlatformName, err = util.GetPlatformName(); err != nil {
+			return util.Agent{}, err
+		}
+	}
 	// Set agent type
 	newAgent.ClusterType = constants.AgentType
-	// Set project id
-	newAgent.ProjectId = pid
 	// Get namespace
-	newAgent.Namespace, newAgent.NsExists = k8s.ValidNs(constants.ChaosAgentLabel)
+	var err error
+	if newAgent.Namespace == "" {
+		newAgent.Namespace, newAgent.NsExists, err = k8s.ValidNs(constants.ChaosAgentLabel)
+	} else {
+		newAgent.NsExists, err = k8s.CheckNs(newAgent.Namespace, constants.ChaosAgentLabel)
+	}
+	if err != nil {
+		return util.Agent{}, err
+	}
 
-	return newAgent
+	return newAgent, nil
+}
+
+// agentNameInput takes a non empty agent name as input
+func agentNameInput(p prompt.Prompter) (string, error) {
+	name, err := p.Input("🤷 Agent Name", "")
+	for err == nil && name == "" {
+		p.Println("⛔ Agent name cannot be empty. Please enter a valid name.")
+		name, err = p.Input("🤷 Agent Name", "")
+	}
+	return name, err
 }
 
 type AgentData struct {
 	Data AgentList `json:"data"`
 }
 type AgentDetails struct {
-	AgentName    string `json:"cluster_name"`
-	IsActive     bool   `json:"is_active"`
-	IsRegistered bool   `json:"is_registered"`
-	ClusterID    string `json:"cluster_id"`
+	AgentName      string `json:"cluster_name"`
+	IsActive       bool   `json:"is_active"`
+	IsRegistered   bool   `json:"is_registered"`
+	ClusterID      string `json:"cluster_id"`
+	Description    string `json:"description"`
+	PlatformName   string `json:"platform_name"`
+	AgentNamespace string `json:"agent_namespace"`
+	ServiceAccount string `json:"serviceaccount"`
+	AgentScope     string `json:"agent_scope"`
+	ClusterType    string `json:"cluster_type"`
+	CreatedAt      string `json:"created_at"`
+	// UpdatedAt is the time at which the agent was last updated on the server
+	UpdatedAt string `json:"updated_at"`
 }
 type AgentList struct {
 	GetAgent []AgentDetails `json:"getCluster"`
 }
 
-// AgentExists checks if an agent of the given name already exists
-func AgentExists(pid, agentName string, t util.Token, cred util.Credentials) bool {
+const getAgentsQuery = `query getCluster($projectID: String!) {
+  getCluster(project_id: $projectID) {
+    cluster_id
+    cluster_name
+    description
+    is_active
+    is_registered
+    platform_name
+    agent_namespace
+    serviceaccount
+    agent_scope
+    cluster_type
+    created_at
+    updated_at
+  }
+}`
+
+// getAgentNamesQuery only fetches the names of the agents, so that
+// the registration doesn't depend on the other fields of the schema
+const getAgentNamesQuery = `query getCluster($projectID: String!) {
+  getCluster(project_id: $projectID) {
+    cluster_name
+  }
+}`
+
+const registerAgentMutation = `mutation userClusterReg($clusterInput: ClusterInput!) {
+  userClusterReg(clusterInput: $clusterInput) {
+    cluster_id
+    cluster_name
+    token
+  }
+}`
 
+// getAgents fetches the agents connected to the specified project
+// using the given query
+func getAgents(query, pid string, s *util.Session) (AgentData, error) {
 	var agents AgentData
-	client := resty.New()
-	bodyData := `{"query":"query{\n  getCluster(project_id: \"` + fmt.Sprintf("%s", pid) + `\"){\n    cluster_name\n  }\n}"}`
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", t.AccessToken)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(bodyData).
-		// SetResult automatic unmarshalling for the request,
-		// if response status code is between 200 and 299
-		SetResult(&agents).
-		Post(
-			fmt.Sprintf(
-				"%s/chaos/api/graphql/query",
-				cred.Host,
-			),
-		)
-	if err != nil || !resp.IsSuccess() {
-		return true
+	err := util.NewGraphQLClient(s, "chaos").Do(query, map[string]interface{}{
+		"projectID": pid,
+	}, &agents.Data)
+	if err != nil {
+		return AgentData{}, fmt.Errorf("fetching agents failed: %w", err)
+	}
+	return agents, nil
+}
+
+// AgentExists checks if an agent of the given name already exists
+func AgentExists(pid, agentName string, s *util.Session) (bool, error) {
+	agents, err := getAgents(getAgentNamesQuery, pid, s)
+	if err != nil {
+		return false, err
 	}
 	for i, _ := range agents.Data.GetAgent {
 		if agentName == agents.Data.GetAgent[i].AgentName {
-			return true
+			return true, nil
 		}
 	}
-	return false
+	return false, nil
+}
+
+// ListAgents returns the agents connected to the specified project
+func ListAgents(pid string, s *util.Session) ([]AgentDetails, error) {
+	agents, err := getAgents(getAgentsQuery, pid, s)
+	if err != nil {
+		return nil, err
+	}
+	return agents.Data.GetAgent, nil
 }
 
 // GetAgentList lists the agent connected to the specified project
-func GetAgentList(pid string, t util.Token, cred util.Credentials) {
-	var agents AgentData
-	client := resty.New()
-	bodyData := `{"query":"query{\n  getCluster(project_id: \"` + fmt.Sprintf("%s", pid) + `\"){\n    cluster_name\n  }\n}"}`
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", t.AccessToken)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(bodyData).
-		// SetResult automatic unmarshalling for the request,
-		// if response status code is between 200 and 299
-		SetResult(&agents).
-		Post(
-			fmt.Sprintf(
-				"%s/chaos/api/graphql/query",
-				cred.Host,
-			),
-		)
-	if err != nil || !resp.IsSuccess() {
-		fmt.Println(err)
+func GetAgentList(pid string, s *util.Session) error {
+	agents, err := ListAgents(pid, s)
+	if err != nil {
+		return err
 	}
 	fmt.Println("\n📘 Registered agents list -----------")
 	fmt.Println()
-	for i, _ := range agents.Data.GetAgent {
-		fmt.Println("-", agents.Data.GetAgent[i].AgentName)
+	for i, _ := range agents {
+		fmt.Println("-", agents[i].AgentName)
 	}
 	fmt.Println("\n-------------------------------------")
+	return nil
 }
 
 // RegisterAgent registers the agent with the given details
-func RegisterAgent(c util.Agent, t util.Token, cred util.Credentials) (AgentRegistrationData, error) {
+func RegisterAgent(c util.Agent, s *util.Session) (AgentRegistrationData, error) {
 	var cr AgentRegistrationData
-	client := resty.New()
-	bodyData := `{"query":"mutation {\n  userClusterReg(clusterInput: \n    { \n    cluster_name: \"` + fmt.Sprintf("%s", c.AgentName) + `\", \n    description: \"` + fmt.Sprintf("%s", c.Description) + `\",\n  \tplatform_name: \"` + fmt.Sprintf("%s", c.PlatformName) + `\",\n    project_id: \"` + fmt.Sprintf("%s", c.ProjectId) + `\",\n    cluster_type: \"` + fmt.Sprintf("%s", c.ClusterType) + `\",\n  agent_scope: \"` + fmt.Sprintf("%s", c.Mode) + `\",\n    agent_namespace: \"` + fmt.Sprintf("%s", c.Namespace) + `\",\n    serviceaccount: \"` + fmt.Sprintf("%s", c.ServiceAccount) + `\",\n    agent_ns_exists: ` + fmt.Sprintf("%t", c.NsExists) + `,\n    agent_sa_exists: ` + fmt.Sprintf("%t", c.SAExists) + `,\n  }){\n    cluster_id\n    cluster_name\n    token\n  }\n}"}`
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", t.AccessToken)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(bodyData).
-		// SetResult automatic unmarshalling for the request,
-		// if response status code is between 200 and 299
-		SetResult(&cr).
-		Post(
-			fmt.Sprintf(
-				"%s/chaos/api/graphql/query",
-				cred.Host,
-			),
-		)
-	if err != nil || !resp.IsSuccess() {
-		fmt.Println(err)
-		fmt.Println(resp.IsSuccess())
-		return AgentRegistrationData{}, err
+	err := util.NewGraphQLClient(s, "chaos").Do(registerAgentMutation, map[string]interface{}{
+		"clusterInput": map[string]interface{}{
+			"cluster_name":    c.AgentName,
+			"description":     c.Description,
+			"platform_name":   c.PlatformName,
+			"project_id":      c.ProjectId,
+			"cluster_type":    c.ClusterType,
+			"agent_scope":     c.Mode,
+			"agent_namespace": c.Namespace,
+			"serviceaccount":  c.ServiceAccount,
+			"agent_ns_exists": c.NsExists,
+			"agent_sa_exists": c.SAExists,
+		},
+	}, &cr.Data)
+	if err != nil {
+		return AgentRegistrationData{}, fmt.Errorf("%w: %v", ErrRegistrationFailed, err)
+	}
+	// Data field is null in response if the agent isn't registered
+	if (cr.Data == AgentRegister{}) {
+		return AgentRegistrationData{}, ErrRegistrationFailed
 	}
 	return cr, nil
 }
+
+const disconnectAgentMutation = `mutation deleteClusterReg($clusterID: String!) {
+  deleteClusterReg(cluster_id: $clusterID)
+}`
+
+// GetAgent fetches the details of the agent with the given name or id
+func GetAgent(pid, agent string, s *util.Session) (AgentDetails, error) {
+	agents, err := getAgents(getAgentsQuery, pid, s)
+	if err != nil {
+		return AgentDetails{}, err
+	}
+	for _, a := range agents.Data.GetAgent {
+		if a.ClusterID == agent || a.AgentName == agent {
+			return a, nil
+		}
+	}
+	return AgentDetails{}, fmt.Errorf("%w: %s", ErrAgentNotFound, agent)
+}
+
+// DisconnectAgent deregisters the agent with the given id on the server
+func DisconnectAgent(clusterID string, s *util.Session) error {
+	err := util.NewGraphQLClient(s, "chaos").Do(disconnectAgentMutation, map[string]interface{}{
+		"clusterID": clusterID,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("disconnecting agent %s failed: %w", clusterID, err)
+	}
+	return nil
+}
+
+// UninstallAgent removes the subscriber of the given agent from the
+// cluster and returns the objects deleted. k8s.ErrSubscriberNotFound
+// is returned if the subscriber doesn't exist
+func UninstallAgent(agent AgentDetails) ([]string, error) {
+	if agent.AgentNamespace == "" {
+		return nil, fmt.Errorf("namespace of agent %s is unknown", agent.AgentName)
+	}
+	deleted, err := k8s.DeleteSubscriber(
+		agent.AgentNamespace,
+		constants.ChaosAgentLabel,
+		[]string{constants.ChaosAgentConfig},
+		[]string{constants.ChaosAgentSecret},
+	)
+	// The objects provisioned for the agent are removed even if
+	// the subscriber is already gone
+	if err != nil && !errors.Is(err, k8s.ErrSubscriberNotFound) {
+		return deleted, err
+	}
+	// Remove the objects provisioned for the agent by the CLI, if any
+	removed, terr := k8s.Teardown(context.Background(), k8s.ProvisionOptions{
+		Mode:           agent.AgentScope,
+		Namespace:      agent.AgentNamespace,
+		ServiceAccount: agent.ServiceAccount,
+		Owner:          agent.AgentName,
+	})
+	if terr != nil {
+		err = terr
+	}
+	return append(deleted, removed...), err
+}
+
+// DeleteAgent removes the subscriber of the agent with the given
+// name or id from the cluster and deregisters it on the server
+func DeleteAgent(pid, agent string, s *util.Session) ([]string, error) {
+	details, err := GetAgent(pid, agent, s)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := UninstallAgent(details)
+	if errors.Is(err, k8s.ErrSubscriberNotFound) {
+		// The subscriber has already been removed from the cluster
+		fmt.Printf("⚠️ %v, deregistering the agent\n", err)
+	} else if err != nil {
+		return deleted, err
+	}
+	return deleted, DisconnectAgent(details.ClusterID, s)
+}
@@ -0,0 +1,265 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
+	"text/template"
+
+	"github.com/mayadata-io/cli-utils/pkg/common"
+	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
+	"github.com/mayadata-io/cli-utils/pkg/constants"
+)
+
+// Default values used for bulk registration
+const (
+	DefaultNameTemplate = "{{.Context}}"
+	DefaultWorkers      = 5
+)
+
+// Status of the registration of a cluster
+const (
+	StatusSucceeded = "Succeeded"
+	StatusFailed    = "Failed"
+)
+
+// BulkRegisterOptions holds the details required to register
+// agents on several clusters at once
+type BulkRegisterOptions struct {
+	// Project is the name or the id of the project, it's required
+	// since the registration runs without asking for input
+	Project        string `json:"project" yaml:"project"`
+	Description    string `json:"description" yaml:"description"`
+	PlatformName   string `json:"platformName" yaml:"platformName"`
+	Mode           string `json:"mode" yaml:"mode"`
+	Namespace      string `json:"namespace" yaml:"namespace"`
+	ServiceAccount string `json:"serviceAccount" yaml:"serviceAccount"`
+
+	// Kubeconfig holding the contexts of the clusters, the standard
+	// loading rules are followed if empty
+	Kubeconfig string `json:"kubeconfig" yaml:"kubeconfig"`
+	// Contexts of the clusters to be registered
+	Contexts []string `json:"contexts" yaml:"contexts"`
+	// ContextPattern selects the contexts whose names match the
+	// pattern, it's used if Contexts is empty. The pattern is a glob
+	// in the syntax of path.Match e.g. prod-* or eu-?-cluster.
+	ContextPattern string `json:"contextPattern" yaml:"contextPattern"`
+	// NameTemplate is the template of the agent names, the
+	// context name is available as {{.Context}}
+	NameTemplate string `json:"nameTemplate" yaml:"nameTemplate"`
+	// Workers is the number of clusters registered concurrently
+	Workers int `json:"workers" yaml:"workers"`
+}
+
+// ClusterResult holds the outcome of registering an agent on a cluster
+type ClusterResult struct {
+	Context   string `json:"context"`
+	AgentName string `json:"agent_name"`
+	ClusterID string `json:"cluster_id"`
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	Err       error  `json:"-"`
+}
+
+// nameData is passed to the agent name template
+type nameData struct {
+	Context string
+}
+
+// BulkRegister registers an agent on each of the selected kubeconfig
+// contexts concurrently and returns the result per cluster. The error
+// returned is only about the options, failures on a cluster are
+// reported in its result.
+func BulkRegister(s *common.Session, opts BulkRegisterOptions) ([]ClusterResult, error) {
+	if err := (RegisterOptions{Mode: opts.Mode}).Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Project == "" {
+		return nil, fmt.Errorf("project is required to register agents in bulk")
+	}
+	contexts, err := selectContexts(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.NameTemplate == "" {
+		opts.NameTemplate = DefaultNameTemplate
+	}
+	nameTmpl, err := template.New("name").Option("missingkey=error").Parse(opts.NameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.Mode == "" {
+		opts.Mode = constants.DefaultMode
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = constants.DefaultNs
+	}
+	if opts.ServiceAccount == "" {
+		opts.ServiceAccount = constants.DefaultSA
+	}
+
+	// Fetch project id
+	user, err := GetProjectDetails(s, "chaos")
+	if err != nil {
+		return nil, fmt.Errorf("fetching project details failed: %w", err)
+	}
+	pid, err := GetProjectID(user, opts.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ClusterResult, len(contexts))
+	names := make(map[string]string, len(contexts))
+	for i, kubeContext := range contexts {
+		var name bytes.Buffer
+		if err := nameTmpl.Execute(&name, nameData{Context: kubeContext}); err != nil {
+			return nil, fmt.Errorf("invalid name template: %w", err)
+		}
+		if other, ok := names[name.String()]; ok {
+			return nil, fmt.Errorf("contexts %s and %s get the same agent name %q", other, kubeContext, name.String())
+		}
+		names[name.String()] = kubeContext
+		results[i] = ClusterResult{Context: kubeContext, AgentName: name.String()}
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := &results[i]
+				r.ClusterID, r.Err = registerCluster(s, pid, r.Context, r.AgentName, opts)
+				if r.Err != nil {
+					r.Status = StatusFailed
+					r.Error = r.Err.Error()
+				} else {
+					r.Status = StatusSucceeded
+				}
+			}
+		}()
+	}
+	for i := range results {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
+
+// selectContexts returns the contexts chosen by the options
+func selectContexts(opts BulkRegisterOptions) ([]string, error) {
+	if len(opts.Contexts) > 0 {
+		return opts.Contexts, nil
+	}
+	if opts.ContextPattern == "" {
+		return nil, errors.New("either contexts or a context pattern is required")
+	}
+	all, err := k8s.Contexts(opts.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	var contexts []string
+	for _, kubeContext := range all {
+		ok, err := path.Match(opts.ContextPattern, kubeContext)
+		if err != nil {
+			return nil, fmt.Errorf("invalid context pattern: %w", err)
+		}
+		if ok {
+			contexts = append(contexts, kubeContext)
+		}
+	}
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("no context matches %s", opts.ContextPattern)
+	}
+	return contexts, nil
+}
+
+// registerCluster registers an agent on the cluster of the given
+// context and returns the id of the agent
+func registerCluster(s *common.Session, pid, kubeContext, agentName string, opts BulkRegisterOptions) (string, error) {
+	client := k8s.NewClient(k8s.ClientOptions{
+		Kubeconfig: opts.Kubeconfig,
+		Context:    kubeContext,
+	})
+	exists, err := AgentExists(pid, agentName, s)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("%w: %s", ErrAgentExists, agentName)
+	}
+
+	newAgent := common.Agent{
+		AgentName:      agentName,
+		Mode:           opts.Mode,
+		Description:    opts.Description,
+		PlatformName:   opts.PlatformName,
+		ProjectId:      pid,
+		ClusterType:    constants.AgentType,
+		Namespace:      opts.Namespace,
+		ServiceAccount: opts.ServiceAccount,
+	}
+	if newAgent.PlatformName == "" {
+		newAgent.PlatformName = common.DiscoverClusterPlatform(client)
+	}
+	if newAgent.NsExists, err = client.CheckNs(newAgent.Namespace, constants.ChaosAgentLabel); err != nil {
+		return "", err
+	}
+	if newAgent.SAExists, err = client.SAExists(newAgent.Namespace, newAgent.ServiceAccount); err != nil {
+		return "", err
+	}
+	report := client.Preflight(context.Background(), k8s.PreflightOptions{
+		Mode:      newAgent.Mode,
+		Namespace: newAgent.Namespace,
+	})
+	if err := report.Err(); err != nil {
+		return "", err
+	}
+
+	// Register agent, the permissions needed to install it were
+	// checked by the preflight
+	agent, err := RegisterAgent(newAgent, s)
+	if err != nil {
+		return "", err
+	}
+	// An agent which can't be installed isn't left registered
+	applied, err := installClusterAgent(s, client, newAgent, agent.Data.UserAgentReg.Token)
+	if err != nil {
+		return "", removeOnError(client, s, agent.Data.UserAgentReg.ClusterID, newAgent.Namespace, applied, err)
+	}
+	return agent.Data.UserAgentReg.ClusterID, nil
+}
+
+// installClusterAgent applies the registration yaml of the registered
+// agent on the cluster of the client and waits for it to be ready. It
+// returns whether the registration yaml was applied to the cluster.
+func installClusterAgent(s *common.Session, client *k8s.Client, newAgent common.Agent, token string) (bool, error) {
+	manifest, err := common.FetchManifest(s, token, constants.ChaosYamlPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to download registration yaml: %w", err)
+	}
+	matrix, err := client.ManifestPermissionMatrix(context.Background(), manifest, newAgent.Namespace)
+	if err != nil {
+		return false, err
+	}
+	if err := matrix.Err(); err != nil {
+		return false, err
+	}
+	if _, err := client.ApplyManifest(manifest); err != nil {
+		return true, fmt.Errorf("failed in applying registration yaml: %w", err)
+	}
+	// Wait for the subscriber to be ready, the clusters are registered
+	// concurrently so the progress isn't printed and the failures are
+	// reported in the result of the cluster
+	return true, client.WaitForAgentReady(context.Background(), newAgent.Namespace, constants.ChaosAgentLabel, k8s.DefaultReadyTimeout, ioutil.Discard)
+}
@@ -0,0 +1,204 @@
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mayadata-io/cli-utils/pkg/constants"
+)
+
+// DefaultCacheTTL is how long a cached manifest is used
+// before it's revalidated
+const DefaultCacheTTL = 24 * time.Hour
+
+// HubCache keeps the manifests of the hubs on disk, under
+// <hub>/<chart>/<experiment>/<file type>.json in Dir
+type HubCache struct {
+	Dir string
+	// TTL of the entries, DefaultCacheTTL is used if it's 0
+	TTL time.Duration
+}
+
+// DefaultHubCacheDir returns the directory of the cache
+// inside the cache directory of the user
+func DefaultHubCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, constants.ConfigDirName, "hub"), nil
+}
+
+// cacheEntry is a manifest stored in the cache
+type cacheEntry struct {
+	Data      string    `json:"data"`
+	ETag      string    `json:"etag,omitempty"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// cacheName escapes the name so that it's a single path element
+func cacheName(name string) string {
+	escaped := url.PathEscape(name)
+	if strings.Trim(escaped, ".") == "" {
+		escaped = strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return escaped
+}
+
+func (c *HubCache) path(hub, chart, experiment, fileType string) string {
+	return filepath.Join(c.Dir, cacheName(hub), cacheName(chart), cacheName(experiment), cacheName(fileType)+".json")
+}
+
+func (c *HubCache) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return DefaultCacheTTL
+}
+
+// get returns the entry of the key, nil if it isn't cached
+func (c *HubCache) get(hub, chart, experiment, fileType string) *cacheEntry {
+	data, err := ioutil.ReadFile(c.path(hub, chart, experiment, fileType))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil {
+		return nil
+	}
+	return &entry
+}
+
+// put stores the entry, writing it to a temporary file first so
+// that concurrent readers never see it half written
+func (c *HubCache) put(hub, chart, experiment, fileType string, entry cacheEntry) error {
+	path := c.path(hub, chart, experiment, fileType)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Clear removes the cached manifests
+func (c *HubCache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// conditionalHub is a hub able to revalidate a manifest with its ETag
+type conditionalHub interface {
+	GetYAMLConditional(chart, experiment, fileType, etag string) (data, newETag string, notModified bool, err error)
+}
+
+// CachedHub serves the manifests of a hub from the cache while they're
+// fresh. Stale ones are revalidated with their ETag if the hub supports
+// it, and used as they are with a warning if the hub can't be reached.
+type CachedHub struct {
+	Hub   ChartHub
+	Name  string
+	Cache *HubCache
+}
+
+// NewCachedHub returns the hub caching the manifests under the given name
+func NewCachedHub(hub ChartHub, name string, cache *HubCache) *CachedHub {
+	return &CachedHub{Hub: hub, Name: name, Cache: cache}
+}
+
+// ListPackages lists the packages of the hub, the cached list is
+// used with a warning if the hub can't be reached
+func (h *CachedHub) ListPackages() ([]PackageData, error) {
+	entry := h.Cache.get(h.Name, "", "", "packages")
+	if entry != nil && time.Since(entry.FetchedAt) < h.Cache.ttl() {
+		var packages []PackageData
+		if json.Unmarshal([]byte(entry.Data), &packages) == nil {
+			return packages, nil
+		}
+	}
+	packages, err := h.Hub.ListPackages()
+	if err != nil {
+		if entry != nil {
+			var cached []PackageData
+			if json.Unmarshal([]byte(entry.Data), &cached) == nil {
+				staleWarning("packages of "+h.Name, entry, err)
+				return cached, nil
+			}
+		}
+		return nil, err
+	}
+	if data, err := json.Marshal(packages); err == nil {
+		if err := h.Cache.put(h.Name, "", "", "packages", cacheEntry{Data: string(data), FetchedAt: time.Now()}); err != nil {
+			fmt.Println("⚠️ Caching the packages failed:", err)
+		}
+	}
+	return packages, nil
+}
+
+// staleWarning tells that the cached entry is used as the hub failed
+func staleWarning(what string, entry *cacheEntry, err error) {
+	fmt.Printf("⚠️ Using the %s cached at %s as the hub failed: %v\n", what, entry.FetchedAt.Format(time.RFC3339), err)
+}
+
+// GetYAML returns the manifest from the cache or the hub
+func (h *CachedHub) GetYAML(chart, experiment, fileType string) (string, error) {
+	entry := h.Cache.get(h.Name, chart, experiment, fileType)
+	if entry != nil && time.Since(entry.FetchedAt) < h.Cache.ttl() {
+		return entry.Data, nil
+	}
+
+	var (
+		fresh cacheEntry
+		err   error
+	)
+	if c, ok := h.Hub.(conditionalHub); ok {
+		var etag string
+		if entry != nil {
+			etag = entry.ETag
+		}
+		var notModified bool
+		fresh.Data, fresh.ETag, notModified, err = c.GetYAMLConditional(chart, experiment, fileType, etag)
+		if err == nil && notModified {
+			fresh.Data = entry.Data
+		}
+	} else {
+		fresh.Data, err = h.Hub.GetYAML(chart, experiment, fileType)
+	}
+	if err != nil {
+		if entry != nil {
+			staleWarning(fileType+" of "+experiment, entry, err)
+			return entry.Data, nil
+		}
+		return "", err
+	}
+
+	// Empty manifests aren't kept, so that they're fetched again
+	if strings.TrimSpace(fresh.Data) == "" {
+		return fresh.Data, nil
+	}
+	fresh.FetchedAt = time.Now()
+	if err := h.Cache.put(h.Name, chart, experiment, fileType, fresh); err != nil {
+		fmt.Println("⚠️ Caching", fileType, "of", experiment, "failed:", err)
+	}
+	return fresh.Data, nil
+}
@@ -0,0 +1,151 @@
+package chaos
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// etagHub is a conditional hub serving a single manifest, it
+// answers not modified when the ETag of the manifest is sent
+type etagHub struct {
+	data  string
+	etag  string
+	err   error
+	calls []string
+}
+
+func (h *etagHub) ListPackages() ([]PackageData, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	return []PackageData{{ChartName: "generic", Experiments: []string{"pod-delete"}}}, nil
+}
+
+func (h *etagHub) GetYAML(chart, experiment, fileType string) (string, error) {
+	data, _, _, err := h.GetYAMLConditional(chart, experiment, fileType, "")
+	return data, err
+}
+
+func (h *etagHub) GetYAMLConditional(chart, experiment, fileType, etag string) (string, string, bool, error) {
+	h.calls = append(h.calls, etag)
+	if h.err != nil {
+		return "", "", false, h.err
+	}
+	if etag != "" && etag == h.etag {
+		return "", etag, true, nil
+	}
+	return h.data, h.etag, false, nil
+}
+
+func newTestCache(t *testing.T, ttl time.Duration) *HubCache {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "hub-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return &HubCache{Dir: dir, TTL: ttl}
+}
+
+// age makes the cached entry look fetched the given time ago
+func age(t *testing.T, cache *HubCache, chart, experiment, fileType string, d time.Duration) {
+	t.Helper()
+	entry := cache.get("hub", chart, experiment, fileType)
+	if entry == nil {
+		t.Fatalf("%s of %s isn't cached", fileType, experiment)
+	}
+	entry.FetchedAt = time.Now().Add(-d)
+	if err := cache.put("hub", chart, experiment, fileType, *entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachedHubFreshEntry(t *testing.T) {
+	hub := &etagHub{data: "kind: ChaosEngine\n", etag: `"v1"`}
+	cache := newTestCache(t, time.Hour)
+	cached := NewCachedHub(hub, "hub", cache)
+
+	for i := 0; i < 2; i++ {
+		got, err := cached.GetYAML("generic", "pod-delete", FileTypeEngine)
+		if err != nil || got != hub.data {
+			t.Fatalf("GetYAML() = %q, %v, want %q", got, err, hub.data)
+		}
+	}
+	if len(hub.calls) != 1 {
+		t.Errorf("hub called %d times while the entry is fresh, want once", len(hub.calls))
+	}
+
+	// Past the TTL the entry is revalidated
+	age(t, cache, "generic", "pod-delete", FileTypeEngine, 2*time.Hour)
+	if _, err := cached.GetYAML("generic", "pod-delete", FileTypeEngine); err != nil {
+		t.Fatal(err)
+	}
+	if len(hub.calls) != 2 {
+		t.Errorf("hub called %d times after the TTL, want twice", len(hub.calls))
+	}
+}
+
+func TestCachedHubRevalidatesWithETag(t *testing.T) {
+	hub := &etagHub{data: "kind: ChaosEngine\n", etag: `"v1"`}
+	cache := newTestCache(t, time.Hour)
+	cached := NewCachedHub(hub, "hub", cache)
+	if _, err := cached.GetYAML("generic", "pod-delete", FileTypeEngine); err != nil {
+		t.Fatal(err)
+	}
+	age(t, cache, "generic", "pod-delete", FileTypeEngine, 2*time.Hour)
+
+	// The hub answers not modified, the cached manifest is reused
+	hub.data = ""
+	got, err := cached.GetYAML("generic", "pod-delete", FileTypeEngine)
+	if err != nil || got != "kind: ChaosEngine\n" {
+		t.Fatalf("GetYAML() = %q, %v, want the cached manifest", got, err)
+	}
+	if len(hub.calls) != 2 || hub.calls[1] != `"v1"` {
+		t.Errorf("ETags sent = %q, want the cached one on revalidation", hub.calls)
+	}
+	entry := cache.get("hub", "generic", "pod-delete", FileTypeEngine)
+	if entry == nil || time.Since(entry.FetchedAt) > time.Minute {
+		t.Error("entry isn't fresh again after being revalidated")
+	}
+}
+
+func TestCachedHubSkipsEmptyManifests(t *testing.T) {
+	hub := &etagHub{data: "  \n"}
+	cached := NewCachedHub(hub, "hub", newTestCache(t, time.Hour))
+	for i := 0; i < 2; i++ {
+		if _, err := cached.GetYAML("generic", "pod-delete", FileTypeEngine); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(hub.calls) != 2 {
+		t.Errorf("hub called %d times, want the empty manifest fetched again", len(hub.calls))
+	}
+}
+
+func TestCachedHubStaleFallback(t *testing.T) {
+	hub := &etagHub{data: "kind: ChaosEngine\n", etag: `"v1"`}
+	cache := newTestCache(t, time.Hour)
+	cached := NewCachedHub(hub, "hub", cache)
+	if _, err := cached.GetYAML("generic", "pod-delete", FileTypeEngine); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.ListPackages(); err != nil {
+		t.Fatal(err)
+	}
+	age(t, cache, "generic", "pod-delete", FileTypeEngine, 2*time.Hour)
+	age(t, cache, "", "", "packages", 2*time.Hour)
+
+	hub.err = errors.New("hub unreachable")
+	if got, err := cached.GetYAML("generic", "pod-delete", FileTypeEngine); err != nil || got != "kind: ChaosEngine\n" {
+		t.Errorf("GetYAML() = %q, %v, want the stale manifest", got, err)
+	}
+	if packages, err := cached.ListPackages(); err != nil || len(packages) != 1 {
+		t.Errorf("ListPackages() = %v, %v, want the stale packages", packages, err)
+	}
+	if _, err := cached.GetYAML("generic", "pod-cpu-hog", FileTypeEngine); !errors.Is(err, hub.err) {
+		t.Errorf("GetYAML() error = %v for an uncached manifest, want %v", err, hub.err)
+	}
+}
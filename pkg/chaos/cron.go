@@ -0,0 +1,134 @@
+package chaos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/robfig/cron/v3"
+)
+
+// CronWorkflowOptions decide when and how the scheduled chaos runs
+type CronWorkflowOptions struct {
+	// Schedule in cron format e.g. "0 2 * * 1-5" for weekday nights,
+	// descriptors like @daily are accepted as well
+	Schedule string `json:"schedule" yaml:"schedule"`
+	// Timezone the schedule is evaluated in e.g. "Asia/Kolkata",
+	// the timezone of the workflow controller is used if empty
+	Timezone string `json:"timezone" yaml:"timezone"`
+	// ConcurrencyPolicy is one of Allow, Forbid or Replace
+	ConcurrencyPolicy string `json:"concurrencyPolicy" yaml:"concurrencyPolicy"`
+	// StartingDeadlineSeconds is how late a missed run can still be started
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds" yaml:"startingDeadlineSeconds"`
+	// History limits of the succeeded and failed runs kept
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit" yaml:"successfulJobsHistoryLimit"`
+	FailedJobsHistoryLimit     *int32 `json:"failedJobsHistoryLimit" yaml:"failedJobsHistoryLimit"`
+	// Suspend creates the schedule without running it
+	Suspend bool `json:"suspend" yaml:"suspend"`
+}
+
+var concurrencyPolicies = []v1alpha1.ConcurrencyPolicy{
+	v1alpha1.AllowConcurrent,
+	v1alpha1.ForbidConcurrent,
+	v1alpha1.ReplaceConcurrent,
+}
+
+// Validate checks the schedule, the timezone and the limits
+func (o CronWorkflowOptions) Validate() error {
+	if strings.TrimSpace(o.Schedule) == "" {
+		return fmt.Errorf("schedule is required")
+	}
+	if strings.Contains(o.Schedule, "TZ=") {
+		return fmt.Errorf("invalid schedule %q: use the timezone option instead of TZ", o.Schedule)
+	}
+	if _, err := cron.ParseStandard(o.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule %q: %v", o.Schedule, err)
+	}
+	if o.Timezone != "" {
+		if _, err := time.LoadLocation(o.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %v", o.Timezone, err)
+		}
+	}
+	if o.ConcurrencyPolicy != "" {
+		valid := false
+		for _, policy := range concurrencyPolicies {
+			valid = valid || string(policy) == o.ConcurrencyPolicy
+		}
+		if !valid {
+			return fmt.Errorf("invalid concurrency policy %q, must be one of Allow, Forbid or Replace", o.ConcurrencyPolicy)
+		}
+	}
+	if o.StartingDeadlineSeconds != nil && *o.StartingDeadlineSeconds < 0 {
+		return fmt.Errorf("startingDeadlineSeconds can't be negative")
+	}
+	if o.SuccessfulJobsHistoryLimit != nil && *o.SuccessfulJobsHistoryLimit < 0 {
+		return fmt.Errorf("successfulJobsHistoryLimit can't be negative")
+	}
+	if o.FailedJobsHistoryLimit != nil && *o.FailedJobsHistoryLimit < 0 {
+		return fmt.Errorf("failedJobsHistoryLimit can't be negative")
+	}
+	return nil
+}
+
+// NextRuns returns the next n times the schedule runs after the given time
+func (o CronWorkflowOptions) NextRuns(after time.Time, n int) ([]time.Time, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	schedule, err := cron.ParseStandard(o.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	if o.Timezone != "" {
+		location, err := time.LoadLocation(o.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		after = after.In(location)
+	}
+	runs := make([]time.Time, 0, n)
+	for i := 0; i < n; i++ {
+		after = schedule.Next(after)
+		runs = append(runs, after)
+	}
+	return runs, nil
+}
+
+// GenerateCronWorkflow generates a CronWorkflow running the workflow
+// of GenerateWorkflow on the given schedule
+func GenerateCronWorkflow(wf_inputs GenerateWorkflowInputs, opts CronWorkflowOptions) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	workflow, err := buildWorkflow(wf_inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	var yaml v1alpha1.CronWorkflow
+	yaml.APIVersion = "argoproj.io/v1alpha1"
+	yaml.Kind = "CronWorkflow"
+	yaml.ObjectMeta = workflow.ObjectMeta
+	yaml.Spec = v1alpha1.CronWorkflowSpec{
+		WorkflowSpec:               workflow.Spec,
+		Schedule:                   opts.Schedule,
+		Timezone:                   opts.Timezone,
+		ConcurrencyPolicy:          v1alpha1.ConcurrencyPolicy(opts.ConcurrencyPolicy),
+		StartingDeadlineSeconds:    opts.StartingDeadlineSeconds,
+		SuccessfulJobsHistoryLimit: opts.SuccessfulJobsHistoryLimit,
+		FailedJobsHistoryLimit:     opts.FailedJobsHistoryLimit,
+		Suspend:                    opts.Suspend,
+	}
+	// The workflows created on schedule carry the labels as well
+	yaml.Spec.WorkflowMetadata = workflow.ObjectMeta.DeepCopy()
+	yaml.Spec.WorkflowMetadata.Name = ""
+	yaml.Spec.WorkflowMetadata.Namespace = ""
+
+	yamlByte, err := marshalManifest(yaml)
+	if err != nil {
+		return nil, err
+	}
+
+	return yamlByte, nil
+}
@@ -0,0 +1,160 @@
+package chaos
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func int64Ptr(i int64) *int64 { return &i }
+
+func TestCronWorkflowOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CronWorkflowOptions
+		wantErr string
+	}{
+		{name: "valid", opts: CronWorkflowOptions{Schedule: "0 2 * * 1-5", Timezone: "Asia/Kolkata", ConcurrencyPolicy: "Forbid"}},
+		{name: "descriptor", opts: CronWorkflowOptions{Schedule: "@daily"}},
+		{name: "zero limits", opts: CronWorkflowOptions{Schedule: "@hourly", SuccessfulJobsHistoryLimit: int32Ptr(0), FailedJobsHistoryLimit: int32Ptr(0)}},
+		{name: "empty schedule", opts: CronWorkflowOptions{Schedule: " "}, wantErr: "schedule is required"},
+		{name: "invalid schedule", opts: CronWorkflowOptions{Schedule: "0 25 * * *"}, wantErr: "invalid schedule"},
+		{name: "too many fields", opts: CronWorkflowOptions{Schedule: "0 0 2 * * 1"}, wantErr: "invalid schedule"},
+		{name: "TZ prefix", opts: CronWorkflowOptions{Schedule: "TZ=Asia/Kolkata 0 2 * * *"}, wantErr: "use the timezone option"},
+		{name: "CRON_TZ prefix", opts: CronWorkflowOptions{Schedule: "CRON_TZ=UTC 0 2 * * *"}, wantErr: "use the timezone option"},
+		{name: "invalid timezone", opts: CronWorkflowOptions{Schedule: "@daily", Timezone: "Mars/Olympus"}, wantErr: "invalid timezone"},
+		{name: "invalid concurrency policy", opts: CronWorkflowOptions{Schedule: "@daily", ConcurrencyPolicy: "forbid"}, wantErr: "invalid concurrency policy"},
+		{name: "negative deadline", opts: CronWorkflowOptions{Schedule: "@daily", StartingDeadlineSeconds: int64Ptr(-1)}, wantErr: "startingDeadlineSeconds"},
+		{name: "negative successful limit", opts: CronWorkflowOptions{Schedule: "@daily", SuccessfulJobsHistoryLimit: int32Ptr(-1)}, wantErr: "successfulJobsHistoryLimit"},
+		{name: "negative failed limit", opts: CronWorkflowOptions{Schedule: "@daily", FailedJobsHistoryLimit: int32Ptr(-3)}, wantErr: "failedJobsHistoryLimit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronWorkflowOptionsNextRuns(t *testing.T) {
+	after := time.Date(2021, time.March, 5, 12, 0, 0, 0, time.UTC) // a Friday
+	tests := []struct {
+		name    string
+		opts    CronWorkflowOptions
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "weekdays",
+			opts: CronWorkflowOptions{Schedule: "0 2 * * 1-5"},
+			want: []string{"2021-03-08T02:00:00Z", "2021-03-09T02:00:00Z", "2021-03-10T02:00:00Z"},
+		},
+		{
+			name: "timezone",
+			opts: CronWorkflowOptions{Schedule: "30 1 * * *", Timezone: "Asia/Kolkata"},
+			want: []string{"2021-03-06T01:30:00+05:30", "2021-03-07T01:30:00+05:30", "2021-03-08T01:30:00+05:30"},
+		},
+		{name: "invalid schedule", opts: CronWorkflowOptions{Schedule: "every day"}, wantErr: true},
+		{name: "invalid timezone", opts: CronWorkflowOptions{Schedule: "@daily", Timezone: "Nowhere"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := tt.opts.NextRuns(after, 3)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NextRuns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(runs) != len(tt.want) {
+				t.Fatalf("NextRuns() = %v, want %v", runs, tt.want)
+			}
+			for i, run := range runs {
+				if got := run.Format(time.RFC3339); got != tt.want[i] {
+					t.Errorf("run %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateCronWorkflow(t *testing.T) {
+	inputs := GenerateWorkflowInputs{
+		WorkName:      "nightly-chaos",
+		WorkNamespace: "litmus",
+		ClusterID:     "cluster-1",
+		Hub:           newTestHub("generic/pod-delete"),
+		Stages: []WorkflowStage{
+			{Name: "pods", Experiments: []ExperimentRef{{ChartName: "generic", Experiment: "pod-delete"}}},
+		},
+	}
+	opts := CronWorkflowOptions{
+		Schedule:                   "0 2 * * 1-5",
+		Timezone:                   "Asia/Kolkata",
+		ConcurrencyPolicy:          "Forbid",
+		SuccessfulJobsHistoryLimit: int32Ptr(3),
+	}
+	data, err := GenerateCronWorkflow(inputs, opts)
+	if err != nil {
+		t.Fatalf("GenerateCronWorkflow() error = %v", err)
+	}
+
+	// The field names are the ones of the CRD
+	var fields struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Schedule         string `json:"schedule"`
+			Timezone         string `json:"timezone"`
+			WorkflowMetadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"workflowMetadata"`
+		} `json:"spec"`
+	}
+	if err := k8syaml.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("invalid yaml: %v\n%s", err, data)
+	}
+	if fields.Kind != "CronWorkflow" {
+		t.Errorf("kind = %q, want CronWorkflow", fields.Kind)
+	}
+	if fields.Spec.Schedule != opts.Schedule || fields.Spec.Timezone != opts.Timezone {
+		t.Errorf("spec.schedule = %q, spec.timezone = %q, want %q, %q", fields.Spec.Schedule, fields.Spec.Timezone, opts.Schedule, opts.Timezone)
+	}
+	if fields.Spec.WorkflowMetadata.Labels["cluster_id"] != "cluster-1" {
+		t.Errorf("spec.workflowMetadata.labels = %v, want cluster_id cluster-1", fields.Spec.WorkflowMetadata.Labels)
+	}
+	if fields.Spec.WorkflowMetadata.Name != "" {
+		t.Errorf("spec.workflowMetadata.name = %q, want it empty", fields.Spec.WorkflowMetadata.Name)
+	}
+
+	var cron v1alpha1.CronWorkflow
+	if err := k8syaml.Unmarshal(data, &cron); err != nil {
+		t.Fatal(err)
+	}
+	if cron.Name != "nightly-chaos" || cron.Namespace != "litmus" {
+		t.Errorf("metadata = %s/%s, want litmus/nightly-chaos", cron.Namespace, cron.Name)
+	}
+	if cron.Spec.ConcurrencyPolicy != v1alpha1.ForbidConcurrent {
+		t.Errorf("spec.concurrencyPolicy = %q, want Forbid", cron.Spec.ConcurrencyPolicy)
+	}
+	if cron.Spec.SuccessfulJobsHistoryLimit == nil || *cron.Spec.SuccessfulJobsHistoryLimit != 3 {
+		t.Errorf("spec.successfulJobsHistoryLimit = %v, want 3", cron.Spec.SuccessfulJobsHistoryLimit)
+	}
+	if cron.Spec.WorkflowSpec.Entrypoint != "custom-chaos" {
+		t.Errorf("spec.workflowSpec.entrypoint = %q, want custom-chaos", cron.Spec.WorkflowSpec.Entrypoint)
+	}
+
+	if _, err := GenerateCronWorkflow(inputs, CronWorkflowOptions{Schedule: "TZ=UTC @daily"}); err == nil {
+		t.Error("GenerateCronWorkflow() error = nil for an invalid schedule")
+	}
+}
@@ -0,0 +1,71 @@
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrAgentExists is returned when an agent with the
+	// given name is already connected to the project
+	ErrAgentExists = errors.New("agent already exists")
+
+	// ErrAgentNotFound is returned when no agent matches the given name or id
+	ErrAgentNotFound = errors.New("agent not found")
+
+	// ErrProjectNotFound is returned when no project matches
+	// the given name or id
+	ErrProjectNotFound = errors.New("project not found")
+
+	// ErrRegistrationFailed is returned when the server
+	// doesn't register the agent
+	ErrRegistrationFailed = errors.New("agent registration failed")
+
+	// ErrWorkflowRunNotFound is returned when no run matches the given id
+	ErrWorkflowRunNotFound = errors.New("workflow run not found")
+
+	// ErrWorkflowFailed is returned when a run of a workflow
+	// or the verdict of any of its experiments fails
+	ErrWorkflowFailed = errors.New("workflow failed")
+
+	// ErrInvalidManifest is returned when a manifest of the
+	// hub is empty or can't be parsed
+	ErrInvalidManifest = errors.New("invalid manifest")
+)
+
+// ExperimentError is the error of a manifest of an experiment
+type ExperimentError struct {
+	Experiment string
+	FileType   string
+	Err        error
+}
+
+func (e *ExperimentError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Experiment, e.FileType, e.Err)
+}
+
+func (e *ExperimentError) Unwrap() error {
+	return e.Err
+}
+
+// ExperimentErrors holds the errors of all the experiments of a workflow
+type ExperimentErrors []*ExperimentError
+
+func (e ExperimentErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d manifests can't be used: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Is checks if any of the errors matches the target
+func (e ExperimentErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
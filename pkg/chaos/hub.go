@@ -0,0 +1,232 @@
+package chaos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	util "github.com/mayadata-io/cli-utils/pkg/common"
+)
+
+// File types of an experiment in a hub
+const (
+	FileTypeExperiment = "experiment"
+	FileTypeEngine     = "engine"
+)
+
+// ChartHub is a source of chaos charts
+type ChartHub interface {
+	// ListPackages returns the charts of the hub with their experiments
+	ListPackages() ([]PackageData, error)
+	// GetYAML returns the manifest of the given file type of an experiment
+	GetYAML(chart, experiment, fileType string) (string, error)
+}
+
+// GraphQLHub is a hub connected to the portal, it requires a session
+type GraphQLHub struct {
+	Session   *util.Session
+	ProjectID string
+	HubName   string
+	// HubID is looked up by HubName if it's empty
+	HubID string
+}
+
+// ListPackages lists the packages of the hub through the portal
+func (h *GraphQLHub) ListPackages() ([]PackageData, error) {
+	if h.HubID == "" {
+		status, err := GetHubStatusQuery(h.ProjectID, h.Session)
+		if err != nil {
+			return nil, err
+		}
+		for _, hub := range status.Data.GetHubStatus {
+			if hub.HubName == h.HubName {
+				h.HubID = hub.ID
+			}
+		}
+		if h.HubID == "" {
+			return nil, fmt.Errorf("hub %s not found in the project", h.HubName)
+		}
+	}
+	pkgdata, err := ListPkgDataQuery(h.ProjectID, h.HubID, h.Session)
+	if err != nil {
+		return nil, err
+	}
+	return pkgdata.Data.ListHubPkgData, nil
+}
+
+// GetYAML fetches the manifest through the portal
+func (h *GraphQLHub) GetYAML(chart, experiment, fileType string) (string, error) {
+	yamlData, err := GetYamlData(GenerateWorkflowInputs{
+		Session:        h.Session,
+		ProjectID:      h.ProjectID,
+		HubName:        h.HubName,
+		ChartName:      chart,
+		ExperimentName: &experiment,
+		FileType:       &fileType,
+	})
+	if err != nil {
+		return "", err
+	}
+	return yamlData.Data.GetYAMLData, nil
+}
+
+// GetYAMLConditional fetches the manifest through the portal unless
+// it matches the ETag, notModified is true if it does
+func (h *GraphQLHub) GetYAMLConditional(chart, experiment, fileType, etag string) (data, newETag string, notModified bool, err error) {
+	var result struct {
+		GetYAMLData string `json:"getYAMLData"`
+	}
+	newETag, notModified, err = util.NewGraphQLClient(h.Session, "chaos").DoConditional(getYamlDataQuery, map[string]interface{}{
+		"experimentInput": map[string]interface{}{
+			"ProjectID":      h.ProjectID,
+			"HubName":        h.HubName,
+			"ChartName":      chart,
+			"ExperimentName": experiment,
+			"FileType":       fileType,
+		},
+	}, etag, &result)
+	if err != nil || notModified {
+		return "", newETag, notModified, err
+	}
+	return result.GetYAMLData, newETag, false, nil
+}
+
+// LocalHub reads the charts from a chaos-charts style directory,
+// e.g. a git clone of a hub. The manifests of an experiment are at
+// charts/<chart>/<experiment>/<file type>.yaml under Dir.
+type LocalHub struct {
+	Dir string
+}
+
+// NewLocalHub returns the hub of the given directory after checking
+// it has charts
+func NewLocalHub(dir string) (*LocalHub, error) {
+	info, err := os.Stat(filepath.Join(dir, "charts"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hub %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("invalid hub %s: charts isn't a directory", dir)
+	}
+	return &LocalHub{Dir: dir}, nil
+}
+
+// ListPackages lists the charts having at least one experiment,
+// sorted by name
+func (h *LocalHub) ListPackages() ([]PackageData, error) {
+	charts, err := ioutil.ReadDir(filepath.Join(h.Dir, "charts"))
+	if err != nil {
+		return nil, err
+	}
+	var packages []PackageData
+	for _, chart := range charts {
+		if !chart.IsDir() {
+			continue
+		}
+		dirs, err := ioutil.ReadDir(filepath.Join(h.Dir, "charts", chart.Name()))
+		if err != nil {
+			return nil, err
+		}
+		pkg := PackageData{ChartName: chart.Name()}
+		for _, dir := range dirs {
+			if !dir.IsDir() {
+				continue
+			}
+			path := filepath.Join(h.Dir, "charts", chart.Name(), dir.Name(), FileTypeExperiment+".yaml")
+			if _, err := os.Stat(path); err == nil {
+				pkg.Experiments = append(pkg.Experiments, dir.Name())
+			}
+		}
+		if len(pkg.Experiments) > 0 {
+			sort.Strings(pkg.Experiments)
+			packages = append(packages, pkg)
+		}
+	}
+	return packages, nil
+}
+
+// GetYAML reads the manifest from the directory of the experiment
+func (h *LocalHub) GetYAML(chart, experiment, fileType string) (string, error) {
+	for _, name := range []string{chart, experiment, fileType} {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return "", fmt.Errorf("invalid name %q", name)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(h.Dir, "charts", chart, experiment, fileType+".yaml"))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%s of %s/%s not found in %s", fileType, chart, experiment, h.Dir)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FindExperiment returns the chart of the hub having the experiment
+func FindExperiment(hub ChartHub, experiment string) (string, error) {
+	packages, err := hub.ListPackages()
+	if err != nil {
+		return "", err
+	}
+	var charts []string
+	for _, pkg := range packages {
+		for _, e := range pkg.Experiments {
+			if e == experiment {
+				charts = append(charts, pkg.ChartName)
+			}
+		}
+	}
+	switch len(charts) {
+	case 0:
+		return "", fmt.Errorf("experiment %s not found in the hub", experiment)
+	case 1:
+		return charts[0], nil
+	}
+	return "", fmt.Errorf("experiment %s is in several charts: %s", experiment, strings.Join(charts, ", "))
+}
+
+// DefaultConcurrency is the number of manifests fetched at the same time
+const DefaultConcurrency = 4
+
+// manifestKey identifies a manifest of a hub
+type manifestKey struct {
+	chart, experiment, fileType string
+}
+
+// manifest is a fetched manifest or the error fetching it
+type manifest struct {
+	data string
+	err  error
+}
+
+// fetchManifests fetches the manifests from the hub with at most
+// limit requests at the same time
+func fetchManifests(hub ChartHub, keys []manifestKey, limit int) map[manifestKey]manifest {
+	if limit <= 0 {
+		limit = DefaultConcurrency
+	}
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		manifests = make(map[manifestKey]manifest, len(keys))
+		sem       = make(chan struct{}, limit)
+	)
+	for _, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key manifestKey) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			data, err := hub.GetYAML(key.chart, key.experiment, key.fileType)
+			mu.Lock()
+			manifests[key] = manifest{data: data, err: err}
+			mu.Unlock()
+		}(key)
+	}
+	wg.Wait()
+	return manifests
+}
@@ -0,0 +1,118 @@
+package chaos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// memoryHub is a hub whose charts map the experiments to their
+// manifests by file type
+type memoryHub map[string]map[string]map[string]string
+
+// newTestHub returns a hub with sample manifests of the given
+// experiments, each given as chart/experiment
+func newTestHub(experiments ...string) memoryHub {
+	hub := memoryHub{}
+	for _, name := range experiments {
+		parts := strings.SplitN(name, "/", 2)
+		chart, experiment := parts[0], parts[1]
+		if hub[chart] == nil {
+			hub[chart] = map[string]map[string]string{}
+		}
+		hub[chart][experiment] = map[string]string{
+			FileTypeExperiment: testExperimentYAML(experiment),
+			FileTypeEngine:     testEngineYAML(experiment),
+		}
+	}
+	return hub
+}
+
+func (h memoryHub) ListPackages() ([]PackageData, error) {
+	var packages []PackageData
+	for chart, experiments := range h {
+		pkg := PackageData{ChartName: chart}
+		for experiment := range experiments {
+			pkg.Experiments = append(pkg.Experiments, experiment)
+		}
+		sort.Strings(pkg.Experiments)
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].ChartName < packages[j].ChartName })
+	return packages, nil
+}
+
+func (h memoryHub) GetYAML(chart, experiment, fileType string) (string, error) {
+	data, ok := h[chart][experiment][fileType]
+	if !ok {
+		return "", fmt.Errorf("%s of %s/%s not found", fileType, chart, experiment)
+	}
+	return data, nil
+}
+
+func testExperimentYAML(experiment string) string {
+	return fmt.Sprintf(`apiVersion: litmuschaos.io/v1alpha1
+kind: ChaosExperiment
+metadata:
+  name: %s
+spec:
+  definition:
+    image: litmuschaos/go-runner:latest
+`, experiment)
+}
+
+func testEngineYAML(experiment string) string {
+	return fmt.Sprintf(`apiVersion: litmuschaos.io/v1alpha1
+kind: ChaosEngine
+metadata:
+  name: nginx-chaos
+  namespace: default
+spec:
+  appinfo:
+    appns: default
+    applabel: app=nginx
+    appkind: deployment
+  chaosServiceAccount: %[1]s-sa
+  experiments:
+  - name: %[1]s
+    spec:
+      components:
+        env:
+        - name: TOTAL_CHAOS_DURATION
+          value: "30"
+        - name: CHAOS_INTERVAL
+          value: "10"
+      probe:
+      - name: check-frontend
+        type: httpProbe
+        mode: Continuous
+`, experiment)
+}
+
+func TestFindExperiment(t *testing.T) {
+	hub := newTestHub("generic/pod-delete", "generic/node-drain", "kube-aws/node-drain")
+	tests := []struct {
+		experiment string
+		want       string
+		wantErr    string
+	}{
+		{experiment: "pod-delete", want: "generic"},
+		{experiment: "node-drain", wantErr: "is in several charts: generic, kube-aws"},
+		{experiment: "pod-cpu-hog", wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.experiment, func(t *testing.T) {
+			got, err := FindExperiment(hub, tt.experiment)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("FindExperiment() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("FindExperiment() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
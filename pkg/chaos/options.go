@@ -0,0 +1,91 @@
+package chaos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	ymlparser "gopkg.in/yaml.v2"
+)
+
+// RegisterOptions holds the details required to register an agent.
+// Fields left empty are taken as input from the user.
+type RegisterOptions struct {
+	// Project is the name or the id of the project
+	Project        string `json:"project" yaml:"project"`
+	AgentName      string `json:"agentName" yaml:"agentName"`
+	Description    string `json:"description" yaml:"description"`
+	PlatformName   string `json:"platformName" yaml:"platformName"`
+	Mode           string `json:"mode" yaml:"mode"`
+	Namespace      string `json:"namespace" yaml:"namespace"`
+	ServiceAccount string `json:"serviceAccount" yaml:"serviceAccount"`
+	SkipConfirm    bool   `json:"skipConfirm" yaml:"skipConfirm"`
+	SkipPreflight  bool   `json:"skipPreflight" yaml:"skipPreflight"`
+	// Provision creates the namespace, the service account and
+	// the RBAC of the agent from the CLI before registering it
+	Provision bool `json:"provision" yaml:"provision"`
+
+	// RenderDir, if set, stops the registration after the agent is
+	// registered and writes the manifest split into one file per
+	// resource under this directory instead of applying it
+	RenderDir string `json:"renderDir" yaml:"renderDir"`
+	// Render, if set, stops the registration after the agent is
+	// registered and writes the manifest to it instead of applying it
+	Render io.Writer `json:"-" yaml:"-"`
+}
+
+// LoadRegisterOptions reads the registration options from the
+// given YAML or JSON file
+func LoadRegisterOptions(path string) (RegisterOptions, error) {
+	var opts RegisterOptions
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return RegisterOptions{}, err
+	}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		// Unknown keys are rejected as with YAML, so that typos aren't ignored
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&opts)
+	} else {
+		err = ymlparser.UnmarshalStrict(data, &opts)
+	}
+	if err != nil {
+		return RegisterOptions{}, fmt.Errorf("invalid registration options in %s: %v", path, err)
+	}
+	if err := opts.Validate(); err != nil {
+		return RegisterOptions{}, err
+	}
+	return opts, nil
+}
+
+// Validate checks the values of the fields which are set
+func (o RegisterOptions) Validate() error {
+	if o.Mode != "" && o.Mode != "cluster" && o.Mode != "namespace" {
+		return fmt.Errorf("invalid mode %q, must be either cluster or namespace", o.Mode)
+	}
+	if o.RenderDir != "" && o.Render != nil {
+		return fmt.Errorf("only one of renderDir and render can be set")
+	}
+	// A rendered yaml may be applied elsewhere, so nothing
+	// is created on the cluster for it
+	if o.Provision && (o.RenderDir != "" || o.Render != nil) {
+		return fmt.Errorf("provision can't be set along with renderDir or render")
+	}
+	return nil
+}
+
+// GetProjectID returns the id of the project matching the
+// given name or id
+func GetProjectID(u ProjectDetails, project string) (string, error) {
+	for _, p := range u.Data.GetProjects {
+		if p.ID == project || p.Name == project {
+			return p.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrProjectNotFound, project)
+}
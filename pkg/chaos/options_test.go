@@ -0,0 +1,37 @@
+package chaos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegisterOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    RegisterOptions
+		wantErr string
+	}{
+		{name: "empty", opts: RegisterOptions{}},
+		{name: "cluster mode", opts: RegisterOptions{Mode: "cluster", Provision: true}},
+		{name: "render dir", opts: RegisterOptions{Mode: "namespace", RenderDir: "manifests"}},
+		{name: "invalid mode", opts: RegisterOptions{Mode: "Cluster"}, wantErr: "invalid mode"},
+		{name: "both renders", opts: RegisterOptions{RenderDir: "manifests", Render: &bytes.Buffer{}}, wantErr: "only one of renderDir and render"},
+		{name: "provision with render dir", opts: RegisterOptions{Provision: true, RenderDir: "manifests"}, wantErr: "provision can't be set"},
+		{name: "provision with render", opts: RegisterOptions{Provision: true, Render: &bytes.Buffer{}}, wantErr: "provision can't be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
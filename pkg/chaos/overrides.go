@@ -0,0 +1,230 @@
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// ExperimentOverrides tune an experiment of the workflow, the hub
+// defaults are kept for whatever isn't set
+type ExperimentOverrides struct {
+	// Env of the experiment e.g. TOTAL_CHAOS_DURATION or
+	// PODS_AFFECTED_PERC. The values replace the ones of the engine,
+	// valueFrom included, and the vars it doesn't set are added.
+	Env map[string]string
+	// AppInfo selects the application under chaos
+	AppInfo *AppInfo
+	// Annotations added to the engine
+	Annotations map[string]string
+	// ChaosServiceAccount the experiment runs with
+	ChaosServiceAccount string
+}
+
+// AppInfo is the application targeted by an engine, empty
+// fields keep the value of the hub
+type AppInfo struct {
+	AppNS    string `json:"appns,omitempty"`
+	AppLabel string `json:"applabel,omitempty"`
+	AppKind  string `json:"appkind,omitempty"`
+}
+
+// validateOverrides checks that the overrides are for experiments
+// run in the stages
+func validateOverrides(overrides map[string]ExperimentOverrides, stages []WorkflowStage) error {
+	experiments := stageExperiments(stages)
+	for name := range overrides {
+		if !experiments[name] {
+			return fmt.Errorf("overrides given for %s which isn't an experiment of the workflow", name)
+		}
+	}
+	return nil
+}
+
+// stageExperiments returns the keys of the experiments run in the
+// stages, both their names and chart/experiment
+func stageExperiments(stages []WorkflowStage) map[string]bool {
+	experiments := map[string]bool{}
+	for _, stage := range stages {
+		for _, ref := range stage.Experiments {
+			for _, key := range ref.keys() {
+				experiments[key] = true
+			}
+		}
+	}
+	return experiments
+}
+
+// lookupOverrides returns the overrides of the experiment, the ones
+// given for its chart/experiment take precedence over its name
+func lookupOverrides(overrides map[string]ExperimentOverrides, ref ExperimentRef) (ExperimentOverrides, bool) {
+	for _, key := range ref.keys() {
+		if o, ok := overrides[key]; ok {
+			return o, true
+		}
+	}
+	return ExperimentOverrides{}, false
+}
+
+// customizeEngine applies the overrides and adds the probes of the
+// experiment to the ChaosEngine manifest and returns it
+func customizeEngine(engineYAML, experiment string, o ExperimentOverrides, probes []Probe) (string, error) {
+	data, err := k8syaml.YAMLToJSON([]byte(engineYAML))
+	if err != nil {
+		return "", fmt.Errorf("invalid chaos engine: %w", err)
+	}
+	var engine map[string]interface{}
+	if err := json.Unmarshal(data, &engine); err != nil {
+		return "", fmt.Errorf("invalid chaos engine: %w", err)
+	}
+	if engine == nil {
+		return "", fmt.Errorf("invalid chaos engine: empty manifest")
+	}
+	if err := applyOverrides(engine, o); err != nil {
+		return "", err
+	}
+	if err := addProbes(engine, experiment, probes); err != nil {
+		return "", err
+	}
+
+	data, err = json.Marshal(engine)
+	if err != nil {
+		return "", err
+	}
+	out, err := k8syaml.JSONToYAML(data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// applyOverrides merges the overrides into the parsed engine.
+// Fields of the engine not known here are kept as is.
+func applyOverrides(engine map[string]interface{}, o ExperimentOverrides) error {
+	if len(o.Annotations) > 0 {
+		annotations := child(child(engine, "metadata"), "annotations")
+		for k, v := range o.Annotations {
+			annotations[k] = v
+		}
+	}
+
+	spec := child(engine, "spec")
+	if o.ChaosServiceAccount != "" {
+		spec["chaosServiceAccount"] = o.ChaosServiceAccount
+	}
+	if o.AppInfo != nil {
+		appinfo := child(spec, "appinfo")
+		if o.AppInfo.AppNS != "" {
+			appinfo["appns"] = o.AppInfo.AppNS
+		}
+		if o.AppInfo.AppLabel != "" {
+			appinfo["applabel"] = o.AppInfo.AppLabel
+		}
+		if o.AppInfo.AppKind != "" {
+			appinfo["appkind"] = o.AppInfo.AppKind
+		}
+	}
+
+	if len(o.Env) > 0 {
+		experiments, _ := spec["experiments"].([]interface{})
+		if len(experiments) == 0 {
+			return fmt.Errorf("chaos engine has no experiments to set the env of")
+		}
+		for _, e := range experiments {
+			experiment, ok := e.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid chaos engine: experiment isn't an object")
+			}
+			components := child(child(experiment, "spec"), "components")
+			env, _ := components["env"].([]interface{})
+			components["env"] = mergeEnv(env, o.Env)
+		}
+	}
+	return nil
+}
+
+// addProbes adds the probes to the experiment of the engine with the
+// given name, probes of the hub with the same name are replaced
+func addProbes(engine map[string]interface{}, name string, probes []Probe) error {
+	if len(probes) == 0 {
+		return nil
+	}
+	experiments, _ := child(engine, "spec")["experiments"].([]interface{})
+	for _, e := range experiments {
+		experiment, ok := e.(map[string]interface{})
+		if !ok || experiment["name"] != name {
+			continue
+		}
+		spec := child(experiment, "spec")
+		existing, _ := spec["probe"].([]interface{})
+		replaced := map[string]bool{}
+		for _, p := range probes {
+			replaced[p.Name] = true
+		}
+		var list []interface{}
+		for _, p := range existing {
+			if v, ok := p.(map[string]interface{}); ok && replaced[fmt.Sprint(v["name"])] {
+				continue
+			}
+			list = append(list, p)
+		}
+		for _, p := range probes {
+			data, err := json.Marshal(p)
+			if err != nil {
+				return err
+			}
+			var probe map[string]interface{}
+			if err := json.Unmarshal(data, &probe); err != nil {
+				return err
+			}
+			list = append(list, probe)
+		}
+		spec["probe"] = list
+		return nil
+	}
+	return fmt.Errorf("chaos engine doesn't run the experiment %s to add the probes to", name)
+}
+
+// mergeEnv sets the values of the env vars already listed and
+// appends the others sorted by name
+func mergeEnv(env []interface{}, overrides map[string]string) []interface{} {
+	set := map[string]bool{}
+	for _, e := range env {
+		v, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := v["name"].(string)
+		if value, ok := overrides[name]; ok {
+			v["value"] = value
+			delete(v, "valueFrom")
+			set[name] = true
+		}
+	}
+	var names []string
+	for name := range overrides {
+		if !set[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, map[string]interface{}{
+			"name":  name,
+			"value": overrides[name],
+		})
+	}
+	return env
+}
+
+// child returns the object under the key, creating it if it's missing
+func child(obj map[string]interface{}, key string) map[string]interface{} {
+	if c, ok := obj[key].(map[string]interface{}); ok {
+		return c
+	}
+	c := map[string]interface{}{}
+	obj[key] = c
+	return c
+}
@@ -0,0 +1,261 @@
+package chaos
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+func TestMergeEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       []interface{}
+		overrides map[string]string
+		want      []interface{}
+	}{
+		{
+			name: "replaces the value",
+			env: []interface{}{
+				map[string]interface{}{"name": "TOTAL_CHAOS_DURATION", "value": "30"},
+				map[string]interface{}{"name": "CHAOS_INTERVAL", "value": "10"},
+			},
+			overrides: map[string]string{"TOTAL_CHAOS_DURATION": "120"},
+			want: []interface{}{
+				map[string]interface{}{"name": "TOTAL_CHAOS_DURATION", "value": "120"},
+				map[string]interface{}{"name": "CHAOS_INTERVAL", "value": "10"},
+			},
+		},
+		{
+			name: "drops valueFrom",
+			env: []interface{}{
+				map[string]interface{}{"name": "APP_NS", "valueFrom": map[string]interface{}{"fieldRef": map[string]interface{}{"fieldPath": "metadata.namespace"}}},
+			},
+			overrides: map[string]string{"APP_NS": "shop"},
+			want: []interface{}{
+				map[string]interface{}{"name": "APP_NS", "value": "shop"},
+			},
+		},
+		{
+			name: "appends the others sorted",
+			env: []interface{}{
+				map[string]interface{}{"name": "CHAOS_INTERVAL", "value": "10"},
+			},
+			overrides: map[string]string{"PODS_AFFECTED_PERC": "50", "FORCE": "true", "CHAOS_INTERVAL": "5"},
+			want: []interface{}{
+				map[string]interface{}{"name": "CHAOS_INTERVAL", "value": "5"},
+				map[string]interface{}{"name": "FORCE", "value": "true"},
+				map[string]interface{}{"name": "PODS_AFFECTED_PERC", "value": "50"},
+			},
+		},
+		{
+			name:      "empty env",
+			overrides: map[string]string{"FORCE": "false"},
+			want: []interface{}{
+				map[string]interface{}{"name": "FORCE", "value": "false"},
+			},
+		},
+		{
+			name: "keeps what isn't a var",
+			env: []interface{}{
+				"invalid",
+				map[string]interface{}{"name": "FORCE", "value": "true"},
+			},
+			overrides: map[string]string{"FORCE": "false"},
+			want: []interface{}{
+				"invalid",
+				map[string]interface{}{"name": "FORCE", "value": "false"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeEnv(tt.env, tt.overrides); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// parseEngine parses the engine into a map
+func parseEngine(t *testing.T, engineYAML string) map[string]interface{} {
+	t.Helper()
+	var engine map[string]interface{}
+	if err := k8syaml.Unmarshal([]byte(engineYAML), &engine); err != nil {
+		t.Fatalf("invalid engine: %v\n%s", err, engineYAML)
+	}
+	return engine
+}
+
+func TestCustomizeEngineOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides ExperimentOverrides
+		want      map[string]interface{}
+	}{
+		{
+			name:      "partial appinfo",
+			overrides: ExperimentOverrides{AppInfo: &AppInfo{AppLabel: "app=cart"}},
+			want: map[string]interface{}{
+				"appinfo": map[string]interface{}{"appns": "default", "applabel": "app=cart", "appkind": "deployment"},
+			},
+		},
+		{
+			name:      "whole appinfo",
+			overrides: ExperimentOverrides{AppInfo: &AppInfo{AppNS: "shop", AppLabel: "app=cart", AppKind: "statefulset"}},
+			want: map[string]interface{}{
+				"appinfo": map[string]interface{}{"appns": "shop", "applabel": "app=cart", "appkind": "statefulset"},
+			},
+		},
+		{
+			name:      "empty appinfo",
+			overrides: ExperimentOverrides{AppInfo: &AppInfo{}},
+			want: map[string]interface{}{
+				"appinfo": map[string]interface{}{"appns": "default", "applabel": "app=nginx", "appkind": "deployment"},
+			},
+		},
+		{
+			name:      "service account",
+			overrides: ExperimentOverrides{ChaosServiceAccount: "litmus-admin"},
+			want:      map[string]interface{}{"chaosServiceAccount": "litmus-admin"},
+		},
+		{
+			name:      "nothing",
+			overrides: ExperimentOverrides{},
+			want: map[string]interface{}{
+				"chaosServiceAccount": "pod-delete-sa",
+				"appinfo":             map[string]interface{}{"appns": "default", "applabel": "app=nginx", "appkind": "deployment"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := customizeEngine(testEngineYAML("pod-delete"), "pod-delete", tt.overrides, nil)
+			if err != nil {
+				t.Fatalf("customizeEngine() error = %v", err)
+			}
+			spec := parseEngine(t, out)["spec"].(map[string]interface{})
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(spec[key], want) {
+					t.Errorf("spec.%s = %v, want %v", key, spec[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomizeEngineEnvAndAnnotations(t *testing.T) {
+	out, err := customizeEngine(testEngineYAML("pod-delete"), "pod-delete", ExperimentOverrides{
+		Env:         map[string]string{"TOTAL_CHAOS_DURATION": "120", "PODS_AFFECTED_PERC": "50"},
+		Annotations: map[string]string{"team": "payments"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("customizeEngine() error = %v", err)
+	}
+	engine := parseEngine(t, out)
+	annotations, _ := engine["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	if annotations["team"] != "payments" {
+		t.Errorf("metadata.annotations = %v, want team: payments", annotations)
+	}
+	experiment := engine["spec"].(map[string]interface{})["experiments"].([]interface{})[0].(map[string]interface{})
+	env := experiment["spec"].(map[string]interface{})["components"].(map[string]interface{})["env"]
+	want := []interface{}{
+		map[string]interface{}{"name": "TOTAL_CHAOS_DURATION", "value": "120"},
+		map[string]interface{}{"name": "CHAOS_INTERVAL", "value": "10"},
+		map[string]interface{}{"name": "PODS_AFFECTED_PERC", "value": "50"},
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("env = %v, want %v", env, want)
+	}
+	// The probes of the hub are kept
+	if probes, _ := experiment["spec"].(map[string]interface{})["probe"].([]interface{}); len(probes) != 1 {
+		t.Errorf("probes = %v, want the probe of the hub", probes)
+	}
+}
+
+func TestCustomizeEngineErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  string
+		wantErr string
+	}{
+		{name: "invalid yaml", engine: "spec: [", wantErr: "invalid chaos engine"},
+		{name: "empty", engine: "", wantErr: "invalid chaos engine"},
+		{name: "no experiments", engine: "kind: ChaosEngine\nspec: {}\n", wantErr: "no experiments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := customizeEngine(tt.engine, "pod-delete", ExperimentOverrides{Env: map[string]string{"FORCE": "true"}}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("customizeEngine() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOverrides(t *testing.T) {
+	stages := []WorkflowStage{{Name: "pods", Experiments: []ExperimentRef{{ChartName: "generic", Experiment: "pod-delete"}}}}
+	for _, key := range []string{"pod-delete", "generic/pod-delete"} {
+		if err := validateOverrides(map[string]ExperimentOverrides{key: {}}, stages); err != nil {
+			t.Errorf("validateOverrides() error = %v for %s", err, key)
+		}
+	}
+	for _, key := range []string{"pod-cpu-hog", "kube-aws/pod-delete"} {
+		if err := validateOverrides(map[string]ExperimentOverrides{key: {}}, stages); err == nil {
+			t.Errorf("validateOverrides() error = nil for %s which isn't in the workflow", key)
+		}
+	}
+}
+
+func TestBuildWorkflowOverridesByChart(t *testing.T) {
+	hub := newTestHub("generic/node-drain", "kube-aws/node-drain", "generic/pod-delete")
+	wf, err := buildWorkflow(GenerateWorkflowInputs{
+		Hub: hub,
+		Stages: []WorkflowStage{
+			{Name: "drain", Experiments: []ExperimentRef{
+				{ChartName: "generic", Experiment: "node-drain"},
+				{ChartName: "generic", Experiment: "pod-delete"},
+			}},
+			{Name: "aws", Experiments: []ExperimentRef{{ChartName: "kube-aws", Experiment: "node-drain"}}},
+		},
+		Overrides: map[string]ExperimentOverrides{
+			"node-drain":          {Env: map[string]string{"TOTAL_CHAOS_DURATION": "60"}},
+			"kube-aws/node-drain": {Env: map[string]string{"TOTAL_CHAOS_DURATION": "300"}},
+		},
+		Probes: map[string][]Probe{
+			"generic/node-drain": {testProbe(HTTPProbe, nil)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("buildWorkflow() error = %v", err)
+	}
+	tests := []struct {
+		template   string
+		wantEnv    string
+		wantProbes int
+	}{
+		{template: "generic-node-drain", wantEnv: "60", wantProbes: 2},
+		{template: "kube-aws-node-drain", wantEnv: "300", wantProbes: 1},
+		{template: "pod-delete", wantEnv: "30", wantProbes: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			var engineYAML string
+			for _, tmpl := range wf.Spec.Templates {
+				if tmpl.Name == tt.template {
+					engineYAML = tmpl.Inputs.Artifacts[0].Raw.Data
+				}
+			}
+			experiment := parseEngine(t, engineYAML)["spec"].(map[string]interface{})["experiments"].([]interface{})[0].(map[string]interface{})
+			spec := experiment["spec"].(map[string]interface{})
+			env := spec["components"].(map[string]interface{})["env"].([]interface{})
+			if got := env[0].(map[string]interface{})["value"]; got != tt.wantEnv {
+				t.Errorf("TOTAL_CHAOS_DURATION = %v, want %s", got, tt.wantEnv)
+			}
+			if got := len(spec["probe"].([]interface{})); got != tt.wantProbes {
+				t.Errorf("%d probes, want %d", got, tt.wantProbes)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package chaos
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Types of the probes
+const (
+	HTTPProbe = "httpProbe"
+	CmdProbe  = "cmdProbe"
+	K8sProbe  = "k8sProbe"
+	PromProbe = "promProbe"
+)
+
+// Modes of the probes, i.e. when they run
+const (
+	// ProbeModeSOT runs the probe at the start of the test
+	ProbeModeSOT = "SOT"
+	// ProbeModeEOT runs the probe at the end of the test
+	ProbeModeEOT = "EOT"
+	// ProbeModeEdge runs the probe at the start and at the end of the test
+	ProbeModeEdge = "Edge"
+	// ProbeModeContinuous runs the probe all along the test
+	ProbeModeContinuous = "Continuous"
+)
+
+var (
+	probeModes = []string{ProbeModeSOT, ProbeModeEOT, ProbeModeEdge, ProbeModeContinuous}
+
+	numericCriteria = []string{">=", "<=", "==", "!=", ">", "<", "oneOf", "between"}
+	stringCriteria  = []string{"equal", "notEqual", "contains", "matches", "notMatches", "oneOf"}
+	httpCriteria    = []string{"==", "!=", "oneOf"}
+	k8sOperations   = []string{"present", "absent", "create", "delete"}
+)
+
+// Probe checks the resilience criteria of an experiment, the
+// inputs matching its type are required and the others must be nil
+type Probe struct {
+	Name          string           `json:"name"`
+	Type          string           `json:"type"`
+	Mode          string           `json:"mode"`
+	RunProperties RunProperties    `json:"runProperties"`
+	HTTPInputs    *HTTPProbeInputs `json:"httpProbe/inputs,omitempty"`
+	CmdInputs     *CmdProbeInputs  `json:"cmdProbe/inputs,omitempty"`
+	K8sInputs     *K8sProbeInputs  `json:"k8sProbe/inputs,omitempty"`
+	PromInputs    *PromProbeInputs `json:"promProbe/inputs,omitempty"`
+	// Data is the manifest of the resource created by a k8sProbe
+	Data string `json:"data,omitempty"`
+}
+
+// RunProperties decide how a probe is run, durations are in seconds
+type RunProperties struct {
+	ProbeTimeout         int  `json:"probeTimeout"`
+	Interval             int  `json:"interval"`
+	Retry                int  `json:"retry"`
+	ProbePollingInterval int  `json:"probePollingInterval,omitempty"`
+	InitialDelaySeconds  int  `json:"initialDelaySeconds,omitempty"`
+	StopOnFailure        bool `json:"stopOnFailure,omitempty"`
+}
+
+// HTTPProbeInputs calls an URL and checks the response code,
+// exactly one of Get and Post is required
+type HTTPProbeInputs struct {
+	URL                string     `json:"url"`
+	InsecureSkipVerify bool       `json:"insecureSkipVerify,omitempty"`
+	Method             HTTPMethod `json:"method"`
+}
+
+// HTTPMethod is the request sent by an httpProbe
+type HTTPMethod struct {
+	Get  *HTTPGet  `json:"get,omitempty"`
+	Post *HTTPPost `json:"post,omitempty"`
+}
+
+// HTTPGet sends a GET request
+type HTTPGet struct {
+	Criteria     string `json:"criteria"`
+	ResponseCode string `json:"responseCode"`
+}
+
+// HTTPPost sends a POST request with either Body or the content
+// of the file at BodyPath
+type HTTPPost struct {
+	ContentType  string `json:"contentType,omitempty"`
+	Body         string `json:"body,omitempty"`
+	BodyPath     string `json:"bodyPath,omitempty"`
+	Criteria     string `json:"criteria"`
+	ResponseCode string `json:"responseCode"`
+}
+
+// CmdProbeInputs runs a command and compares its output
+type CmdProbeInputs struct {
+	Command string `json:"command"`
+	// Source is the image the command runs in, "inline" runs
+	// it in the experiment pod
+	Source     string     `json:"source,omitempty"`
+	Comparator Comparator `json:"comparator"`
+}
+
+// Comparator compares the output of a probe with Value, Type is
+// one of int, float and string
+type Comparator struct {
+	Type     string `json:"type,omitempty"`
+	Criteria string `json:"criteria"`
+	Value    string `json:"value"`
+}
+
+// K8sProbeInputs checks a kubernetes resource
+type K8sProbeInputs struct {
+	Group         string `json:"group,omitempty"`
+	Version       string `json:"version"`
+	Resource      string `json:"resource"`
+	Namespace     string `json:"namespace,omitempty"`
+	FieldSelector string `json:"fieldSelector,omitempty"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// Operation is one of present, absent, create and delete
+	Operation string `json:"operation"`
+}
+
+// PromProbeInputs runs a prometheus query and compares its result,
+// either Query or QueryPath is required
+type PromProbeInputs struct {
+	Endpoint   string     `json:"endpoint"`
+	Query      string     `json:"query,omitempty"`
+	QueryPath  string     `json:"queryPath,omitempty"`
+	Comparator Comparator `json:"comparator"`
+}
+
+// oneOf checks if the value is one of the valid ones
+func oneOf(value string, valid []string) bool {
+	for _, v := range valid {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the type, the mode, the run properties and
+// the inputs of the probe
+func (p Probe) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("probe name is required")
+	}
+	if !oneOf(p.Mode, probeModes) {
+		return fmt.Errorf("probe %s: invalid mode %q, must be one of %s", p.Name, p.Mode, strings.Join(probeModes, ", "))
+	}
+	if err := p.RunProperties.validate(p.Mode); err != nil {
+		return fmt.Errorf("probe %s: %w", p.Name, err)
+	}
+
+	inputs := 0
+	for _, set := range []bool{p.HTTPInputs != nil, p.CmdInputs != nil, p.K8sInputs != nil, p.PromInputs != nil} {
+		if set {
+			inputs++
+		}
+	}
+	if inputs > 1 {
+		return fmt.Errorf("probe %s: only the inputs of %s can be given", p.Name, p.Type)
+	}
+
+	var err error
+	switch p.Type {
+	case HTTPProbe:
+		if p.HTTPInputs == nil {
+			return fmt.Errorf("probe %s: %s/inputs are required", p.Name, p.Type)
+		}
+		err = p.HTTPInputs.validate()
+	case CmdProbe:
+		if p.CmdInputs == nil {
+			return fmt.Errorf("probe %s: %s/inputs are required", p.Name, p.Type)
+		}
+		err = p.CmdInputs.validate()
+	case K8sProbe:
+		if p.K8sInputs == nil {
+			return fmt.Errorf("probe %s: %s/inputs are required", p.Name, p.Type)
+		}
+		err = p.K8sInputs.validate()
+		if err == nil && p.K8sInputs.Operation == "create" && strings.TrimSpace(p.Data) == "" {
+			err = fmt.Errorf("data is required to create a resource")
+		}
+	case PromProbe:
+		if p.PromInputs == nil {
+			return fmt.Errorf("probe %s: %s/inputs are required", p.Name, p.Type)
+		}
+		err = p.PromInputs.validate()
+	default:
+		return fmt.Errorf("probe %s: invalid type %q, must be one of %s, %s, %s or %s", p.Name, p.Type, HTTPProbe, CmdProbe, K8sProbe, PromProbe)
+	}
+	if err != nil {
+		return fmt.Errorf("probe %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+func (r RunProperties) validate(mode string) error {
+	if r.ProbeTimeout <= 0 {
+		return fmt.Errorf("probeTimeout must be positive")
+	}
+	if r.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if r.Retry < 0 {
+		return fmt.Errorf("retry can't be negative")
+	}
+	if r.InitialDelaySeconds < 0 {
+		return fmt.Errorf("initialDelaySeconds can't be negative")
+	}
+	if r.ProbePollingInterval < 0 {
+		return fmt.Errorf("probePollingInterval can't be negative")
+	}
+	if mode == ProbeModeContinuous && r.ProbePollingInterval == 0 {
+		return fmt.Errorf("probePollingInterval is required in %s mode", mode)
+	}
+	return nil
+}
+
+func (h HTTPProbeInputs) validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid url %q", h.URL)
+	}
+	switch {
+	case h.Method.Get != nil && h.Method.Post != nil:
+		return fmt.Errorf("only one of the get and post methods can be given")
+	case h.Method.Get != nil:
+		return validateResponse(h.Method.Get.Criteria, h.Method.Get.ResponseCode)
+	case h.Method.Post != nil:
+		if h.Method.Post.Body != "" && h.Method.Post.BodyPath != "" {
+			return fmt.Errorf("only one of body and bodyPath can be given")
+		}
+		return validateResponse(h.Method.Post.Criteria, h.Method.Post.ResponseCode)
+	}
+	return fmt.Errorf("the get or post method is required")
+}
+
+// validateResponse checks the criteria on the response code of an httpProbe
+func validateResponse(criteria, code string) error {
+	if !oneOf(criteria, httpCriteria) {
+		return fmt.Errorf("invalid criteria %q, must be one of %s", criteria, strings.Join(httpCriteria, ", "))
+	}
+	if strings.TrimSpace(code) == "" {
+		return fmt.Errorf("responseCode is required")
+	}
+	return nil
+}
+
+func (c CmdProbeInputs) validate() error {
+	if strings.TrimSpace(c.Command) == "" {
+		return fmt.Errorf("command is required")
+	}
+	return c.Comparator.validate(true)
+}
+
+func (k K8sProbeInputs) validate() error {
+	if k.Version == "" {
+		return fmt.Errorf("version is required")
+	}
+	if k.Resource == "" {
+		return fmt.Errorf("resource is required")
+	}
+	if !oneOf(k.Operation, k8sOperations) {
+		return fmt.Errorf("invalid operation %q, must be one of %s", k.Operation, strings.Join(k8sOperations, ", "))
+	}
+	return nil
+}
+
+func (p PromProbeInputs) validate() error {
+	if u, err := url.Parse(p.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q", p.Endpoint)
+	}
+	if (p.Query == "") == (p.QueryPath == "") {
+		return fmt.Errorf("exactly one of query and queryPath is required")
+	}
+	return p.Comparator.validate(false)
+}
+
+// validate checks the criteria against the type of the compared
+// values, promProbe results are always numbers
+func (c Comparator) validate(typed bool) error {
+	criteria := numericCriteria
+	if typed {
+		switch c.Type {
+		case "int", "float":
+		case "string":
+			criteria = stringCriteria
+		default:
+			return fmt.Errorf("invalid comparator type %q, must be one of int, float or string", c.Type)
+		}
+	}
+	if !oneOf(c.Criteria, criteria) {
+		return fmt.Errorf("invalid criteria %q, must be one of %s", c.Criteria, strings.Join(criteria, ", "))
+	}
+	if c.Value == "" {
+		return fmt.Errorf("comparator value is required")
+	}
+	return nil
+}
+
+// lookupProbes returns the probes of the experiment, the ones given
+// for its chart/experiment take precedence over its name
+func lookupProbes(probes map[string][]Probe, ref ExperimentRef) []Probe {
+	for _, key := range ref.keys() {
+		if list, ok := probes[key]; ok {
+			return list
+		}
+	}
+	return nil
+}
+
+// validateProbes checks the probes of each experiment and that
+// the experiments are run in the stages
+func validateProbes(probes map[string][]Probe, stages []WorkflowStage) error {
+	experiments := stageExperiments(stages)
+	for experiment, list := range probes {
+		if !experiments[experiment] {
+			return fmt.Errorf("probes given for %s which isn't an experiment of the workflow", experiment)
+		}
+		names := map[string]bool{}
+		for _, p := range list {
+			if err := p.Validate(); err != nil {
+				return fmt.Errorf("experiment %s: %w", experiment, err)
+			}
+			if names[p.Name] {
+				return fmt.Errorf("experiment %s: probe %s is repeated", experiment, p.Name)
+			}
+			names[p.Name] = true
+		}
+	}
+	return nil
+}
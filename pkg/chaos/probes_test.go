@@ -0,0 +1,232 @@
+package chaos
+
+import (
+	"strings"
+	"testing"
+)
+
+var (
+	testRunProperties = RunProperties{ProbeTimeout: 5, Interval: 2, Retry: 1}
+
+	testHTTPInputs = &HTTPProbeInputs{
+		URL:    "http://frontend.shop.svc:8080/health",
+		Method: HTTPMethod{Get: &HTTPGet{Criteria: "==", ResponseCode: "200"}},
+	}
+	testCmdInputs = &CmdProbeInputs{
+		Command:    "curl -s -o /dev/null -w '%{http_code}' http://frontend",
+		Source:     "inline",
+		Comparator: Comparator{Type: "string", Criteria: "equal", Value: "200"},
+	}
+	testK8sInputs = &K8sProbeInputs{
+		Version:       "v1",
+		Resource:      "pods",
+		Namespace:     "shop",
+		LabelSelector: "app=frontend",
+		Operation:     "present",
+	}
+	testPromInputs = &PromProbeInputs{
+		Endpoint:   "http://prometheus.monitoring:9090",
+		Query:      "sum(rate(http_requests_total{code=~\"5..\"}[1m]))",
+		Comparator: Comparator{Criteria: "<=", Value: "5"},
+	}
+)
+
+// testProbe returns a valid probe of the given type, changed by the
+// given function if it isn't nil
+func testProbe(probeType string, change func(p *Probe)) Probe {
+	p := Probe{Name: "check-" + probeType, Type: probeType, Mode: ProbeModeEdge, RunProperties: testRunProperties}
+	switch probeType {
+	case HTTPProbe:
+		inputs := *testHTTPInputs
+		p.HTTPInputs = &inputs
+	case CmdProbe:
+		inputs := *testCmdInputs
+		p.CmdInputs = &inputs
+	case K8sProbe:
+		inputs := *testK8sInputs
+		p.K8sInputs = &inputs
+	case PromProbe:
+		inputs := *testPromInputs
+		p.PromInputs = &inputs
+	}
+	if change != nil {
+		change(&p)
+	}
+	return p
+}
+
+func TestProbeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   Probe
+		wantErr string
+	}{
+		// Valid probes of each type
+		{name: "httpProbe", probe: testProbe(HTTPProbe, nil)},
+		{name: "httpProbe post", probe: testProbe(HTTPProbe, func(p *Probe) {
+			p.HTTPInputs.Method = HTTPMethod{Post: &HTTPPost{ContentType: "application/json", Body: "{}", Criteria: "!=", ResponseCode: "500"}}
+		})},
+		{name: "cmdProbe", probe: testProbe(CmdProbe, nil)},
+		{name: "cmdProbe int", probe: testProbe(CmdProbe, func(p *Probe) {
+			p.CmdInputs.Comparator = Comparator{Type: "int", Criteria: "between", Value: "200,299"}
+		})},
+		{name: "k8sProbe", probe: testProbe(K8sProbe, nil)},
+		{name: "k8sProbe create", probe: testProbe(K8sProbe, func(p *Probe) {
+			p.K8sInputs.Operation = "create"
+			p.Data = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: probe\n"
+		})},
+		{name: "promProbe", probe: testProbe(PromProbe, nil)},
+		{name: "continuous", probe: testProbe(HTTPProbe, func(p *Probe) {
+			p.Mode = ProbeModeContinuous
+			p.RunProperties.ProbePollingInterval = 1
+		})},
+
+		// Invalid probes
+		{name: "no name", probe: testProbe(HTTPProbe, func(p *Probe) { p.Name = " " }), wantErr: "name is required"},
+		{name: "invalid type", probe: testProbe(HTTPProbe, func(p *Probe) { p.Type = "tcpProbe" }), wantErr: `invalid type "tcpProbe"`},
+		{name: "invalid mode", probe: testProbe(HTTPProbe, func(p *Probe) { p.Mode = "Always" }), wantErr: `invalid mode "Always"`},
+		{name: "mode in another case", probe: testProbe(HTTPProbe, func(p *Probe) { p.Mode = "edge" }), wantErr: "invalid mode"},
+		{name: "continuous without polling interval", probe: testProbe(CmdProbe, func(p *Probe) { p.Mode = ProbeModeContinuous }), wantErr: "probePollingInterval is required in Continuous mode"},
+		{name: "no timeout", probe: testProbe(CmdProbe, func(p *Probe) { p.RunProperties.ProbeTimeout = 0 }), wantErr: "probeTimeout must be positive"},
+		{name: "no interval", probe: testProbe(CmdProbe, func(p *Probe) { p.RunProperties.Interval = 0 }), wantErr: "interval must be positive"},
+		{name: "negative retry", probe: testProbe(CmdProbe, func(p *Probe) { p.RunProperties.Retry = -1 }), wantErr: "retry can't be negative"},
+		{name: "no inputs", probe: testProbe(PromProbe, func(p *Probe) { p.PromInputs = nil }), wantErr: "promProbe/inputs are required"},
+		{name: "inputs of another type", probe: testProbe(HTTPProbe, func(p *Probe) {
+			p.HTTPInputs = nil
+			p.CmdInputs = testCmdInputs
+		}), wantErr: "httpProbe/inputs are required"},
+		{name: "several inputs", probe: testProbe(HTTPProbe, func(p *Probe) { p.CmdInputs = testCmdInputs }), wantErr: "only the inputs of httpProbe can be given"},
+		{name: "all inputs", probe: testProbe(K8sProbe, func(p *Probe) {
+			p.HTTPInputs, p.CmdInputs, p.PromInputs = testHTTPInputs, testCmdInputs, testPromInputs
+		}), wantErr: "only the inputs of k8sProbe can be given"},
+		{name: "http invalid url", probe: testProbe(HTTPProbe, func(p *Probe) { p.HTTPInputs.URL = "frontend:8080" }), wantErr: "invalid url"},
+		{name: "http invalid criteria", probe: testProbe(HTTPProbe, func(p *Probe) {
+			p.HTTPInputs.Method = HTTPMethod{Get: &HTTPGet{Criteria: ">=", ResponseCode: "200"}}
+		}), wantErr: `invalid criteria ">="`},
+		{name: "http no method", probe: testProbe(HTTPProbe, func(p *Probe) { p.HTTPInputs.Method = HTTPMethod{} }), wantErr: "get or post method is required"},
+		{name: "http both methods", probe: testProbe(HTTPProbe, func(p *Probe) {
+			p.HTTPInputs.Method.Post = &HTTPPost{Criteria: "==", ResponseCode: "200"}
+		}), wantErr: "only one of the get and post methods"},
+		{name: "http body and body path", probe: testProbe(HTTPProbe, func(p *Probe) {
+			p.HTTPInputs.Method = HTTPMethod{Post: &HTTPPost{Body: "{}", BodyPath: "/tmp/body", Criteria: "==", ResponseCode: "200"}}
+		}), wantErr: "only one of body and bodyPath"},
+		{name: "cmd no command", probe: testProbe(CmdProbe, func(p *Probe) { p.CmdInputs.Command = "" }), wantErr: "command is required"},
+		{name: "cmd invalid comparator type", probe: testProbe(CmdProbe, func(p *Probe) { p.CmdInputs.Comparator.Type = "bool" }), wantErr: "invalid comparator type"},
+		{name: "cmd numeric criteria on a string", probe: testProbe(CmdProbe, func(p *Probe) { p.CmdInputs.Comparator.Criteria = ">=" }), wantErr: `invalid criteria ">="`},
+		{name: "cmd string criteria on an int", probe: testProbe(CmdProbe, func(p *Probe) {
+			p.CmdInputs.Comparator = Comparator{Type: "int", Criteria: "contains", Value: "2"}
+		}), wantErr: `invalid criteria "contains"`},
+		{name: "cmd no value", probe: testProbe(CmdProbe, func(p *Probe) { p.CmdInputs.Comparator.Value = "" }), wantErr: "comparator value is required"},
+		{name: "k8s create without data", probe: testProbe(K8sProbe, func(p *Probe) { p.K8sInputs.Operation = "create" }), wantErr: "data is required to create a resource"},
+		{name: "k8s invalid operation", probe: testProbe(K8sProbe, func(p *Probe) { p.K8sInputs.Operation = "update" }), wantErr: `invalid operation "update"`},
+		{name: "k8s no resource", probe: testProbe(K8sProbe, func(p *Probe) { p.K8sInputs.Resource = "" }), wantErr: "resource is required"},
+		{name: "prom query and query path", probe: testProbe(PromProbe, func(p *Probe) { p.PromInputs.QueryPath = "/tmp/query" }), wantErr: "exactly one of query and queryPath"},
+		{name: "prom invalid criteria", probe: testProbe(PromProbe, func(p *Probe) { p.PromInputs.Comparator.Criteria = "equal" }), wantErr: `invalid criteria "equal"`},
+		{name: "prom invalid endpoint", probe: testProbe(PromProbe, func(p *Probe) { p.PromInputs.Endpoint = "prometheus" }), wantErr: "invalid endpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.probe.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateProbes(t *testing.T) {
+	stages := []WorkflowStage{{Name: "pods", Experiments: []ExperimentRef{{Experiment: "pod-delete"}}}}
+	tests := []struct {
+		name    string
+		probes  map[string][]Probe
+		wantErr string
+	}{
+		{name: "valid", probes: map[string][]Probe{"pod-delete": {testProbe(HTTPProbe, nil), testProbe(CmdProbe, nil)}}},
+		{name: "experiment not in the workflow", probes: map[string][]Probe{"pod-cpu-hog": {testProbe(HTTPProbe, nil)}}, wantErr: "isn't an experiment of the workflow"},
+		{name: "repeated probe", probes: map[string][]Probe{"pod-delete": {testProbe(HTTPProbe, nil), testProbe(HTTPProbe, nil)}}, wantErr: "probe check-httpProbe is repeated"},
+		{name: "invalid probe", probes: map[string][]Probe{"pod-delete": {testProbe(HTTPProbe, func(p *Probe) { p.Mode = "" })}}, wantErr: "experiment pod-delete: probe check-httpProbe: invalid mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProbes(tt.probes, stages)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateProbes() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateProbes() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// engineProbes returns the probes of the experiment of the
+// customized engine
+func engineProbes(t *testing.T, engineYAML, experiment string) []interface{} {
+	t.Helper()
+	spec := parseEngine(t, engineYAML)["spec"].(map[string]interface{})
+	for _, e := range spec["experiments"].([]interface{}) {
+		e := e.(map[string]interface{})
+		if e["name"] == experiment {
+			probes, _ := e["spec"].(map[string]interface{})["probe"].([]interface{})
+			return probes
+		}
+	}
+	t.Fatalf("experiment %s not found in the engine", experiment)
+	return nil
+}
+
+func TestCustomizeEngineProbes(t *testing.T) {
+	added := testProbe(CmdProbe, nil)
+	out, err := customizeEngine(testEngineYAML("pod-delete"), "pod-delete", ExperimentOverrides{}, []Probe{added})
+	if err != nil {
+		t.Fatalf("customizeEngine() error = %v", err)
+	}
+	probes := engineProbes(t, out, "pod-delete")
+	if len(probes) != 2 {
+		t.Fatalf("probes = %v, want the probe of the hub and %s", probes, added.Name)
+	}
+	probe := probes[1].(map[string]interface{})
+	if probe["name"] != added.Name || probe["type"] != CmdProbe {
+		t.Errorf("added probe = %v, want %s", probe, added.Name)
+	}
+	if _, ok := probe["cmdProbe/inputs"].(map[string]interface{}); !ok {
+		t.Errorf("added probe has no cmdProbe/inputs: %v", probe)
+	}
+}
+
+func TestCustomizeEngineReplacesHubProbe(t *testing.T) {
+	// The engine of the hub has a probe named check-frontend
+	replacement := testProbe(HTTPProbe, func(p *Probe) { p.Name = "check-frontend" })
+	out, err := customizeEngine(testEngineYAML("pod-delete"), "pod-delete", ExperimentOverrides{}, []Probe{replacement, testProbe(K8sProbe, nil)})
+	if err != nil {
+		t.Fatalf("customizeEngine() error = %v", err)
+	}
+	probes := engineProbes(t, out, "pod-delete")
+	var names []string
+	for _, p := range probes {
+		names = append(names, p.(map[string]interface{})["name"].(string))
+	}
+	if strings.Join(names, ",") != "check-frontend,check-k8sProbe" {
+		t.Fatalf("probes = %v, want check-frontend once and check-k8sProbe", names)
+	}
+	// The mode of the hub probe is Continuous, the replacement's is Edge
+	if mode := probes[0].(map[string]interface{})["mode"]; mode != ProbeModeEdge {
+		t.Errorf("mode of check-frontend = %v, want %s of the replacement", mode, ProbeModeEdge)
+	}
+}
+
+func TestCustomizeEngineProbesOfAnotherExperiment(t *testing.T) {
+	_, err := customizeEngine(testEngineYAML("pod-delete"), "pod-cpu-hog", ExperimentOverrides{}, []Probe{testProbe(HTTPProbe, nil)})
+	if err == nil || !strings.Contains(err.Error(), "doesn't run the experiment pod-cpu-hog") {
+		t.Errorf("customizeEngine() error = %v, want the engine not running pod-cpu-hog", err)
+	}
+}
@@ -4,8 +4,7 @@ import (
 	"fmt"
 
 	util "github.com/mayadata-io/cli-utils/pkg/common"
-
-	resty "github.com/go-resty/resty/v2"
+	"github.com/mayadata-io/cli-utils/pkg/common/prompt"
 )
 
 type ProjectDetails struct {
@@ -24,48 +23,42 @@ type Data struct {
 	GetProjects []GetProjects `json:"getProjects"`
 }
 
+const getProjectsQuery = `query getProjects {
+  getProjects {
+    id
+    name
+    members {
+      user_uid
+      role
+    }
+  }
+}`
+
 // GetProjectDetails fetches details of the input user
-func GetProjectDetails(t util.Token, c util.Credentials, product string) (ProjectDetails, interface{}) {
+func GetProjectDetails(s *util.Session, product string) (ProjectDetails, error) {
 	var new ProjectDetails
-	client := resty.New()
-	bodyData := `{"query":"\nquery{\n  getProjects{\n    id\n    name\n    members{\n      user_uid\n      role\n    }\n  }\n}"}`
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", t.AccessToken)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(bodyData).
-		// SetResult automatic unmarshalling for the request,
-		// if response status code is between 200 and 299
-		SetResult(&new).
-		Post(
-			fmt.Sprintf(
-				"%s/%s/api/graphql/query",
-				c.Host,
-				product,
-			),
-		)
-	if err != nil || !resp.IsSuccess() {
-		return ProjectDetails{}, resp.Error()
+	client := util.NewGraphQLClient(s, product)
+	if err := client.Do(getProjectsQuery, nil, &new.Data); err != nil {
+		return ProjectDetails{}, err
 	}
 
 	return new, nil
 }
 
 // GetProject display list of projects and returns the project id based on input
-func GetProject(u ProjectDetails) string {
-	var pid int
-	fmt.Println("\n✨ Projects List:")
-	for index, _ := range u.Data.GetProjects {
-		projectNo := index + 1
-		fmt.Printf("%d.  %s\n", projectNo, u.Data.GetProjects[index].Name)
+func GetProject(u ProjectDetails) (string, error) {
+	if len(u.Data.GetProjects) == 0 {
+		return "", fmt.Errorf("%w: no projects available", ErrProjectNotFound)
+	}
+	names := make([]string, len(u.Data.GetProjects))
+	for i, project := range u.Data.GetProjects {
+		names[i] = project.Name
 	}
-	fmt.Print("\n🔎 Select Project: ")
-	fmt.Scanln(&pid)
-	for pid < 1 || pid > len(u.Data.GetProjects) {
-		fmt.Println("❗ Invalid Project. Please select a correct one.")
-		fmt.Print("\n🔎 Select Project: ")
-		fmt.Scanln(&pid)
+	p := prompt.Default()
+	p.Println("\n✨ Projects List:")
+	pid, err := p.Select("\n🔎 Select Project", names, -1)
+	if err != nil {
+		return "", err
 	}
-	pid = pid - 1
-	return u.Data.GetProjects[pid].ID
+	return u.Data.GetProjects[pid].ID, nil
 }
@@ -0,0 +1,57 @@
+package chaos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mayadata-io/cli-utils/pkg/common/prompt"
+)
+
+func TestGetProject(t *testing.T) {
+	projects := ProjectDetails{Data: Data{GetProjects: []GetProjects{
+		{ID: "p1", Name: "default"},
+		{ID: "p2", Name: "payments"},
+	}}}
+	tests := []struct {
+		name     string
+		projects ProjectDetails
+		answers  []string
+		want     string
+		wantErr  error
+	}{
+		{name: "number", projects: projects, answers: []string{"2"}, want: "p2"},
+		{name: "name", projects: projects, answers: []string{"Default"}, want: "p1"},
+		{name: "empty answer", projects: projects, answers: []string{""}},
+		{name: "out of range", projects: projects, answers: []string{"3"}},
+		{name: "number followed by letters", projects: projects, answers: []string{"1abc"}},
+		{name: "no projects", wantErr: ErrProjectNotFound},
+		{name: "no answer", projects: projects, wantErr: prompt.ErrNoAnswer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := prompt.Default()
+			prompt.SetDefault(prompt.NewScripted(tt.answers...))
+			defer prompt.SetDefault(previous)
+
+			got, err := GetProject(tt.projects)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetProject() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.want == "":
+				// The answer doesn't select any project
+				if err == nil {
+					t.Errorf("GetProject() = %q, want an error", got)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("GetProject() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("GetProject() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,57 +1,225 @@
 package chaos
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
 
 	"github.com/mayadata-io/cli-utils/pkg/common"
 	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
 	"github.com/mayadata-io/cli-utils/pkg/constants"
 )
 
-func Register(t common.Token, c common.Credentials) {
+// Register takes the details of the agent as input and registers it
+func Register(s *common.Session) error {
+	return RegisterWithOptions(s, RegisterOptions{})
+}
+
+// RegisterWithOptions registers the agent using the given options,
+// the options left empty are taken as input from the user
+func RegisterWithOptions(s *common.Session, opts RegisterOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
 	// Fetch project details
-	user, uErr := GetProjectDetails(t, c, "chaos")
-	if uErr != nil {
-		fmt.Printf("\n❌ Fetching project details failed: [%s]", uErr)
-		os.Exit(1)
+	user, err := GetProjectDetails(s, "chaos")
+	if err != nil {
+		return fmt.Errorf("fetching project details failed: %w", err)
 	}
 	// Fetch project id
-	pid := GetProject(user)
+	var pid string
+	if opts.Project == "" {
+		pid, err = GetProject(user)
+	} else {
+		pid, err = GetProjectID(user, opts.Project)
+	}
+	if err != nil {
+		return err
+	}
 	// Get mode of installation as input
-	mode := GetMode()
-	// Check if user has sufficient permissions based on mode
-	fmt.Println("\n🏃 Running prerequisites check....")
-	k8s.ValidateSAPermissions(mode)
+	mode := opts.Mode
+	if mode == "" {
+		if mode, err = GetMode(); err != nil {
+			return err
+		}
+	}
 	// Get agent details as input
-	newAgent := GetAgentDetails(pid, t, c)
+	newAgent, err := FillAgentDetails(common.Agent{
+		AgentName:    opts.AgentName,
+		Description:  opts.Description,
+		PlatformName: opts.PlatformName,
+		ProjectId:    pid,
+		Namespace:    opts.Namespace,
+	}, s)
+	if err != nil {
+		return err
+	}
 	newAgent.Mode = mode
 	// Get service account as input
-	newAgent.ServiceAccount, newAgent.SAExists = k8s.ValidSA(newAgent.Namespace)
+	if opts.ServiceAccount == "" {
+		newAgent.ServiceAccount, newAgent.SAExists, err = k8s.ValidSA(newAgent.Namespace)
+	} else {
+		newAgent.ServiceAccount = opts.ServiceAccount
+		newAgent.SAExists, err = k8s.SAExists(newAgent.Namespace, opts.ServiceAccount)
+	}
+	if err != nil {
+		return err
+	}
+	// Run preflight checks against the cluster. The permissions needed
+	// to install the agent are checked even if the preflight is skipped,
+	// the registration yaml is only available once it's registered. A
+	// rendered yaml may be applied by someone else, so they're skipped.
+	rendering := opts.RenderDir != "" || opts.Render != nil
+	var checks []k8s.Check
+	if !opts.SkipPreflight {
+		checks = k8s.DefaultChecks(k8s.PreflightOptions{
+			Mode:            mode,
+			Namespace:       newAgent.Namespace,
+			PortalURL:       s.Credentials.Host.String(),
+			SkipPermissions: rendering,
+		})
+	} else if !rendering {
+		checks = []k8s.Check{k8s.RBACCheck(mode, newAgent.Namespace)}
+	}
+	if len(checks) > 0 {
+		fmt.Println("\n🏃 Running preflight checks....")
+		report := k8s.RunPreflight(context.Background(), checks)
+		report.Print(nil)
+		if err := report.Err(); err != nil {
+			return err
+		}
+	}
 	// Display details of agent to be connected
-	common.Summary(newAgent, "chaos")
+	if err := common.Summary(newAgent, "chaos"); err != nil {
+		return err
+	}
 	// Confirm before connecting the agent
-	common.Confirm()
+	if !opts.SkipConfirm {
+		if err := common.Confirm(); err != nil {
+			return err
+		}
+	}
+	// Create the namespace, service account and RBAC of the agent,
+	// they're torn down if the agent can't be registered or installed
+	provision := k8s.ProvisionOptions{
+		Mode:           newAgent.Mode,
+		Namespace:      newAgent.Namespace,
+		ServiceAccount: newAgent.ServiceAccount,
+		Owner:          newAgent.AgentName,
+	}
+	if opts.Provision {
+		created, err := k8s.Provision(context.Background(), provision)
+		for _, object := range created {
+			fmt.Println(object, "created")
+		}
+		if err != nil {
+			return teardownOnError(provision, err)
+		}
+		newAgent.NsExists = true
+		newAgent.SAExists = true
+	}
 	// Register agent
-	agent, cerror := RegisterAgent(newAgent, t, c)
-	if cerror != nil {
-		fmt.Printf("\n❌ Agent registration failed: [%s]\n", cerror.Error())
-		os.Exit(1)
-	}
-	// Print error message in case Data field is null in response
-	if (agent.Data == AgentRegister{}) {
-		fmt.Printf("\n🚫 Agent registration failed: [%s]\n", agent.Errors[0].Message)
-		os.Exit(1)
-	}
-	// Apply agent registration yaml
-	yamlOutput, yerror := common.ApplyYaml(agent.Data.UserAgentReg.Token, c, constants.ChaosYamlPath)
-	if yerror != nil {
-		fmt.Printf("\n❌ Failed in applying registration yaml: [%s]\n", yerror.Error())
-		os.Exit(1)
-	}
-	fmt.Println("\n", yamlOutput)
-	// Watch subscriber pod status
-	k8s.WatchPod(newAgent.Namespace, constants.ChaosAgentLabel)
+	agent, err := RegisterAgent(newAgent, s)
+	if err != nil {
+		if opts.Provision {
+			return teardownOnError(provision, err)
+		}
+		return err
+	}
+	// An agent which can't be installed isn't left registered
+	applied, err := installAgent(s, opts, newAgent, agent.Data.UserAgentReg.Token)
+	if err != nil {
+		err = removeOnError(k8s.Default(), s, agent.Data.UserAgentReg.ClusterID, newAgent.Namespace, applied, err)
+		if opts.Provision {
+			return teardownOnError(provision, err)
+		}
+		return err
+	}
+	if rendering {
+		return nil
+	}
 	fmt.Println("\n🚀 Agent Registration Successful!! 🎉")
-	fmt.Println("👉 Kubera agents can be accessed here: " + fmt.Sprintf("%s/%s", c.Host, constants.ChaosAgentPath))
+	fmt.Println("👉 Kubera agents can be accessed here: " + fmt.Sprintf("%s/%s", s.Credentials.Host, constants.ChaosAgentPath))
+	return nil
+}
+
+// removeOnError removes the agent whose installation failed and returns
+// the error of the installation. If the registration yaml was applied,
+// the subscriber is deleted from the cluster first, so that it doesn't
+// block registering the agent again. The agent is then disconnected on
+// the server, it's kept if the subscriber can't be deleted.
+func removeOnError(c *k8s.Client, s *common.Session, clusterID, namespace string, applied bool, err error) error {
+	if applied {
+		_, derr := c.DeleteSubscriber(namespace, constants.ChaosAgentLabel, []string{constants.ChaosAgentConfig}, []string{constants.ChaosAgentSecret})
+		if derr != nil && !errors.Is(derr, k8s.ErrSubscriberNotFound) {
+			return fmt.Errorf("%w; the agent is still registered and installed, please delete it: %v", err, derr)
+		}
+	}
+	if derr := DisconnectAgent(clusterID, s); derr != nil {
+		return fmt.Errorf("%w; the agent is still registered, please remove it from the portal: %v", err, derr)
+	}
+	return err
+}
+
+// teardownOnError deletes the objects provisioned for the agent when
+// registering or installing it failed and returns the error
+func teardownOnError(provision k8s.ProvisionOptions, err error) error {
+	deleted, terr := k8s.Teardown(context.Background(), provision)
+	for _, object := range deleted {
+		fmt.Println(object, "deleted")
+	}
+	if terr != nil {
+		return fmt.Errorf("%w; deleting the provisioned objects failed: %v", err, terr)
+	}
+	return err
+}
+
+// installAgent renders or applies the registration yaml of the
+// registered agent and waits for it to be ready. It returns whether
+// the registration yaml was applied to the cluster.
+func installAgent(s *common.Session, opts RegisterOptions, newAgent common.Agent, token string) (bool, error) {
+	// Write agent registration yaml instead of applying it
+	if opts.RenderDir != "" {
+		files, err := common.RenderYaml(s, token, constants.ChaosYamlPath, opts.RenderDir)
+		if err != nil {
+			return false, fmt.Errorf("failed in rendering registration yaml: %w", err)
+		}
+		fmt.Println("\n📝 Registration yaml written to", opts.RenderDir)
+		for _, file := range files {
+			fmt.Println("-", file)
+		}
+		return false, nil
+	}
+	if opts.Render != nil {
+		if err := common.WriteYaml(s, token, constants.ChaosYamlPath, opts.Render); err != nil {
+			return false, fmt.Errorf("failed in rendering registration yaml: %w", err)
+		}
+		return false, nil
+	}
+	manifest, err := common.FetchManifest(s, token, constants.ChaosYamlPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to download registration yaml: %w", err)
+	}
+	// Check the permissions needed by the registration yaml
+	matrix, err := k8s.ManifestPermissionMatrix(context.Background(), manifest, newAgent.Namespace)
+	if err != nil {
+		return false, err
+	}
+	if err := matrix.Err(); err != nil {
+		fmt.Println("\n🔑 Missing permissions")
+		matrix.Missing().Print(nil)
+		return false, err
+	}
+	// Apply agent registration yaml, the objects applied before
+	// a failure are removed along with the subscriber
+	results, err := k8s.ApplyManifest(manifest)
+	if err != nil {
+		return true, fmt.Errorf("failed in applying registration yaml: %w", err)
+	}
+	fmt.Println()
+	for _, result := range results {
+		fmt.Println(result)
+	}
+	// Wait for the subscriber to be ready
+	return true, k8s.WaitForAgentReady(context.Background(), newAgent.Namespace, constants.ChaosAgentLabel, k8s.DefaultReadyTimeout, nil)
 }
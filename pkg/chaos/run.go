@@ -0,0 +1,449 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	util "github.com/mayadata-io/cli-utils/pkg/common"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// Phases of a workflow run
+const (
+	PhaseRunning   = "Running"
+	PhaseSucceeded = "Succeeded"
+	PhaseFailed    = "Failed"
+	PhaseError     = "Error"
+)
+
+// VerdictFail is the verdict of an experiment which failed
+const VerdictFail = "Fail"
+
+// DefaultWatchInterval is the interval at which a run is polled
+const DefaultWatchInterval = 5 * time.Second
+
+// DefaultWeightage is the weight of an experiment in the resiliency
+// score when none is given
+const DefaultWeightage = 10
+
+// CreateWorkflowOptions holds the details of the workflow to be created
+type CreateWorkflowOptions struct {
+	ProjectID string
+	ClusterID string
+	// Name of the workflow, the name in the manifest is used if empty
+	Name        string
+	Description string
+	// CronSyntax schedules the workflow, it runs once if empty
+	CronSyntax string
+	// Manifest of the workflow in YAML or JSON, e.g. the
+	// output of GenerateWorkflow
+	Manifest []byte
+	// Weightages of the experiments in the resiliency score, by
+	// template name, experiments not listed weigh DefaultWeightage
+	Weightages map[string]int
+}
+
+// WorkflowDetails holds the details of a workflow created on the server
+type WorkflowDetails struct {
+	WorkflowID          string `json:"workflow_id"`
+	WorkflowName        string `json:"workflow_name"`
+	WorkflowDescription string `json:"workflow_description"`
+	CronSyntax          string `json:"cron_syntax"`
+}
+
+// ExperimentResult is the outcome of an experiment in a run
+type ExperimentResult struct {
+	Name                   string `json:"name"`
+	Phase                  string `json:"phase"`
+	Verdict                string `json:"verdict"`
+	ProbeSuccessPercentage int    `json:"probe_success_percentage"`
+	Weightage              int    `json:"weightage"`
+}
+
+// WorkflowRun holds the details of a run of a workflow
+type WorkflowRun struct {
+	WorkflowID    string `json:"workflow_id"`
+	WorkflowName  string `json:"workflow_name"`
+	WorkflowRunID string `json:"workflow_run_id"`
+	ClusterName   string `json:"cluster_name"`
+	Phase         string `json:"phase"`
+	// ResiliencyScore is the mean probe success percentage of the
+	// experiments weighted by their weightage
+	ResiliencyScore float64            `json:"resiliency_score"`
+	StartedAt       string             `json:"started_at"`
+	FinishedAt      string             `json:"finished_at"`
+	LastUpdated     string             `json:"last_updated"`
+	Experiments     []ExperimentResult `json:"-"`
+}
+
+// Finished checks if the run is over
+func (r WorkflowRun) Finished() bool {
+	return r.Phase == PhaseSucceeded || r.Phase == PhaseFailed || r.Phase == PhaseError
+}
+
+// Err returns an error wrapping ErrWorkflowFailed if the run
+// or any of its experiments failed
+func (r WorkflowRun) Err() error {
+	var failed []string
+	for _, e := range r.Experiments {
+		if e.Verdict == VerdictFail {
+			failed = append(failed, e.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: run %s, verdict of %s is %s", ErrWorkflowFailed, r.WorkflowRunID, strings.Join(failed, ", "), VerdictFail)
+	}
+	if r.Finished() && r.Phase != PhaseSucceeded {
+		return fmt.Errorf("%w: run %s is %s", ErrWorkflowFailed, r.WorkflowRunID, r.Phase)
+	}
+	return nil
+}
+
+const createWorkflowMutation = `mutation createChaosWorkFlow($ChaosWorkFlowInput: ChaosWorkFlowInput!) {
+  createChaosWorkFlow(input: $ChaosWorkFlowInput) {
+    workflow_id
+    workflow_name
+    workflow_description
+    cron_syntax
+  }
+}`
+
+const reRunWorkflowMutation = `mutation reRunChaosWorkFlow($workflowID: String!) {
+  reRunChaosWorkFlow(workflowID: $workflowID)
+}`
+
+const getWorkflowRunsQuery = `query getWorkFlowRuns($projectID: String!) {
+  getWorkFlowRuns(project_id: $projectID) {
+    workflow_id
+    workflow_name
+    workflow_run_id
+    cluster_name
+    last_updated
+    phase
+    execution_data
+  }
+}`
+
+const listWorkflowWeightagesQuery = `query ListWorkflow($projectID: String!) {
+  ListWorkflow(project_id: $projectID) {
+    workflow_id
+    weightages {
+      experiment_name
+      weightage
+    }
+  }
+}`
+
+// CreateWorkflow creates the workflow of the given manifest on the
+// server, the agent of the cluster then runs it
+func CreateWorkflow(opts CreateWorkflowOptions, s *util.Session) (WorkflowDetails, error) {
+	manifest, err := k8syaml.YAMLToJSON(opts.Manifest)
+	if err != nil {
+		return WorkflowDetails{}, fmt.Errorf("invalid workflow manifest: %w", err)
+	}
+	var workflow struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Templates []struct {
+				Name      string `json:"name"`
+				Container *struct {
+					Image string `json:"image"`
+				} `json:"container"`
+			} `json:"templates"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(manifest, &workflow); err != nil {
+		return WorkflowDetails{}, fmt.Errorf("invalid workflow manifest: %w", err)
+	}
+	name := opts.Name
+	if name == "" {
+		name = workflow.Metadata.Name
+	}
+	if name == "" {
+		return WorkflowDetails{}, fmt.Errorf("workflow name is required")
+	}
+
+	// Every template running the litmus checker is an experiment
+	var weightages []map[string]interface{}
+	for _, t := range workflow.Spec.Templates {
+		if t.Container == nil || !strings.HasPrefix(t.Container.Image, "litmuschaos/litmus-checker") {
+			continue
+		}
+		weight, ok := opts.Weightages[t.Name]
+		if !ok {
+			weight = DefaultWeightage
+		}
+		weightages = append(weightages, map[string]interface{}{
+			"experiment_name": t.Name,
+			"weightage":       weight,
+		})
+	}
+
+	var result struct {
+		CreateChaosWorkFlow WorkflowDetails `json:"createChaosWorkFlow"`
+	}
+	err = util.NewGraphQLClient(s, "chaos").Do(createWorkflowMutation, map[string]interface{}{
+		"ChaosWorkFlowInput": map[string]interface{}{
+			"workflow_manifest":    string(manifest),
+			"cronSyntax":           opts.CronSyntax,
+			"workflow_name":        name,
+			"workflow_description": opts.Description,
+			"weightages":           weightages,
+			"isCustomWorkflow":     true,
+			"project_id":           opts.ProjectID,
+			"cluster_id":           opts.ClusterID,
+		},
+	}, &result)
+	if err != nil {
+		return WorkflowDetails{}, fmt.Errorf("creating workflow %s failed: %w", name, err)
+	}
+	return result.CreateChaosWorkFlow, nil
+}
+
+// ReRunWorkflow runs the workflow with the given id again
+func ReRunWorkflow(workflowID string, s *util.Session) error {
+	err := util.NewGraphQLClient(s, "chaos").Do(reRunWorkflowMutation, map[string]interface{}{
+		"workflowID": workflowID,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("re-running workflow %s failed: %w", workflowID, err)
+	}
+	return nil
+}
+
+// workflowRunData is a run as returned by the server
+type workflowRunData struct {
+	WorkflowID    string `json:"workflow_id"`
+	WorkflowName  string `json:"workflow_name"`
+	WorkflowRunID string `json:"workflow_run_id"`
+	ClusterName   string `json:"cluster_name"`
+	LastUpdated   string `json:"last_updated"`
+	Phase         string `json:"phase"`
+	ExecutionData string `json:"execution_data"`
+}
+
+// executionData is the state of the run reported by the agent
+type executionData struct {
+	Phase             string `json:"phase"`
+	CreationTimestamp string `json:"creationTimestamp"`
+	FinishedAt        string `json:"finishedAt"`
+	Nodes             map[string]struct {
+		Name         string `json:"name"`
+		TemplateName string `json:"templateName"`
+		Type         string `json:"type"`
+		Phase        string `json:"phase"`
+		ChaosData    *struct {
+			ExperimentName         string `json:"experimentName"`
+			ExperimentVerdict      string `json:"experimentVerdict"`
+			ProbeSuccessPercentage string `json:"probeSuccessPercentage"`
+		} `json:"chaosData"`
+	} `json:"nodes"`
+}
+
+// formatTimestamp converts the unix timestamps of the server to RFC3339
+func formatTimestamp(ts string) string {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sec == 0 {
+		return ts
+	}
+	return time.Unix(sec, 0).UTC().Format(time.RFC3339)
+}
+
+// parseTimestamp parses the timestamps of a run, either unix or RFC3339
+func parseTimestamp(ts string) (time.Time, bool) {
+	if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		return time.Unix(sec, 0), sec != 0
+	}
+	t, err := time.Parse(time.RFC3339, ts)
+	return t, err == nil
+}
+
+// toWorkflowRun converts the run returned by the server. The resiliency
+// score is the sum of the probe success percentages of the experiments
+// times their weightage, divided by the sum of the weightages. The
+// weightages are by template name, experiments not listed weigh
+// DefaultWeightage.
+func (d workflowRunData) toWorkflowRun(weightages map[string]int) WorkflowRun {
+	run := WorkflowRun{
+		WorkflowID:    d.WorkflowID,
+		WorkflowName:  d.WorkflowName,
+		WorkflowRunID: d.WorkflowRunID,
+		ClusterName:   d.ClusterName,
+		Phase:         d.Phase,
+		LastUpdated:   formatTimestamp(d.LastUpdated),
+	}
+	var data executionData
+	if d.ExecutionData == "" || json.Unmarshal([]byte(d.ExecutionData), &data) != nil {
+		return run
+	}
+	if data.Phase != "" {
+		run.Phase = data.Phase
+	}
+	run.StartedAt = formatTimestamp(data.CreationTimestamp)
+	run.FinishedAt = formatTimestamp(data.FinishedAt)
+
+	total, totalWeight := 0, 0
+	for _, node := range data.Nodes {
+		if node.ChaosData == nil {
+			continue
+		}
+		percentage, _ := strconv.Atoi(node.ChaosData.ProbeSuccessPercentage)
+		name := node.ChaosData.ExperimentName
+		if name == "" {
+			name = node.Name
+		}
+		weight, ok := weightages[node.TemplateName]
+		if !ok {
+			weight = DefaultWeightage
+		}
+		run.Experiments = append(run.Experiments, ExperimentResult{
+			Name:                   name,
+			Phase:                  node.Phase,
+			Verdict:                node.ChaosData.ExperimentVerdict,
+			ProbeSuccessPercentage: percentage,
+			Weightage:              weight,
+		})
+		total += weight * percentage
+		totalWeight += weight
+	}
+	if totalWeight > 0 {
+		run.ResiliencyScore = float64(total) / float64(totalWeight)
+	}
+	// The nodes are in no particular order
+	sort.Slice(run.Experiments, func(i, j int) bool {
+		return run.Experiments[i].Name < run.Experiments[j].Name
+	})
+	return run
+}
+
+// workflowWeightages returns the weightages of the experiments of the
+// workflows of the project, by workflow id and template name
+func workflowWeightages(pid string, s *util.Session) (map[string]map[string]int, error) {
+	var result struct {
+		ListWorkflow []struct {
+			WorkflowID string `json:"workflow_id"`
+			Weightages []struct {
+				ExperimentName string `json:"experiment_name"`
+				Weightage      int    `json:"weightage"`
+			} `json:"weightages"`
+		} `json:"ListWorkflow"`
+	}
+	err := util.NewGraphQLClient(s, "chaos").Do(listWorkflowWeightagesQuery, map[string]interface{}{
+		"projectID": pid,
+	}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("fetching workflow weightages failed: %w", err)
+	}
+	weightages := map[string]map[string]int{}
+	for _, workflow := range result.ListWorkflow {
+		weightages[workflow.WorkflowID] = map[string]int{}
+		for _, w := range workflow.Weightages {
+			weightages[workflow.WorkflowID][w.ExperimentName] = w.Weightage
+		}
+	}
+	return weightages, nil
+}
+
+// ListWorkflowRuns returns the runs of the workflows of the given project
+func ListWorkflowRuns(pid string, s *util.Session) ([]WorkflowRun, error) {
+	var result struct {
+		GetWorkFlowRuns []workflowRunData `json:"getWorkFlowRuns"`
+	}
+	err := util.NewGraphQLClient(s, "chaos").Do(getWorkflowRunsQuery, map[string]interface{}{
+		"projectID": pid,
+	}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("fetching workflow runs failed: %w", err)
+	}
+	weightages, err := workflowWeightages(pid, s)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]WorkflowRun, len(result.GetWorkFlowRuns))
+	for i, d := range result.GetWorkFlowRuns {
+		runs[i] = d.toWorkflowRun(weightages[d.WorkflowID])
+	}
+	return runs, nil
+}
+
+// GetWorkflowRun returns the run with the given id
+func GetWorkflowRun(pid, runID string, s *util.Session) (WorkflowRun, error) {
+	runs, err := ListWorkflowRuns(pid, s)
+	if err != nil {
+		return WorkflowRun{}, err
+	}
+	for _, run := range runs {
+		if run.WorkflowRunID == runID {
+			return run, nil
+		}
+	}
+	return WorkflowRun{}, fmt.Errorf("%w: %s", ErrWorkflowRunNotFound, runID)
+}
+
+// WatchWorkflow waits for the first run of the workflow started after
+// since, follows it until it finishes and returns it. An error wrapping
+// ErrWorkflowFailed is returned along with the run if its verdict fails.
+func WatchWorkflow(ctx context.Context, pid, workflowID string, since time.Time, s *util.Session) (WorkflowRun, error) {
+	return watchRun(ctx, pid, s, func(run WorkflowRun) bool {
+		if run.WorkflowID != workflowID {
+			return false
+		}
+		started, ok := parseTimestamp(run.StartedAt)
+		return ok && !started.Before(since.Truncate(time.Second))
+	})
+}
+
+// WatchWorkflowRun follows the run with the given id until it finishes
+// and returns it. An error wrapping ErrWorkflowFailed is returned along
+// with the run if its verdict fails.
+func WatchWorkflowRun(ctx context.Context, pid, runID string, s *util.Session) (WorkflowRun, error) {
+	return watchRun(ctx, pid, s, func(run WorkflowRun) bool {
+		return run.WorkflowRunID == runID
+	})
+}
+
+// watchRun polls the runs until the one matching is finished
+func watchRun(ctx context.Context, pid string, s *util.Session, match func(WorkflowRun) bool) (WorkflowRun, error) {
+	var (
+		current WorkflowRun
+		lastErr error
+		phase   string
+	)
+	err := wait.PollImmediateUntil(DefaultWatchInterval, func() (bool, error) {
+		runs, err := ListWorkflowRuns(pid, s)
+		if err != nil {
+			// The server may be unreachable for a while
+			lastErr = err
+			return false, nil
+		}
+		lastErr = nil
+		for _, run := range runs {
+			if !match(run) {
+				continue
+			}
+			current = run
+			if run.Phase != phase {
+				phase = run.Phase
+				fmt.Println("🏃 Workflow run", run.WorkflowRunID, "is", run.Phase)
+			}
+			return run.Finished(), nil
+		}
+		return false, nil
+	}, ctx.Done())
+	if err != nil {
+		if lastErr != nil {
+			return current, fmt.Errorf("watching workflow run failed: %v: %w", err, lastErr)
+		}
+		return current, fmt.Errorf("watching workflow run failed: %w", err)
+	}
+	fmt.Printf("🏁 Workflow run %s %s, resiliency score %.0f%%\n", current.WorkflowRunID, strings.ToLower(current.Phase), current.ResiliencyScore)
+	return current, current.Err()
+}
@@ -0,0 +1,167 @@
+package chaos
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	util "github.com/mayadata-io/cli-utils/pkg/common"
+)
+
+// testExecutionData returns the execution data of a run whose
+// experiments ran the given templates with the given probe
+// success percentages
+func testExecutionData(t *testing.T, percentages map[string]string) string {
+	t.Helper()
+	nodes := map[string]interface{}{
+		"chaos": map[string]interface{}{"name": "chaos", "templateName": "custom-chaos", "type": "Steps", "phase": "Succeeded"},
+	}
+	for template, percentage := range percentages {
+		nodes["chaos-"+template] = map[string]interface{}{
+			"name":         "chaos[1]." + template,
+			"templateName": template,
+			"type":         "Pod",
+			"phase":        "Succeeded",
+			"chaosData": map[string]interface{}{
+				"experimentName":         template,
+				"experimentVerdict":      "Pass",
+				"probeSuccessPercentage": percentage,
+			},
+		}
+	}
+	data, err := json.Marshal(map[string]interface{}{"phase": "Succeeded", "nodes": nodes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestToWorkflowRunResiliencyScore(t *testing.T) {
+	tests := []struct {
+		name        string
+		percentages map[string]string
+		weightages  map[string]int
+		want        float64
+	}{
+		{
+			name:        "weighted",
+			percentages: map[string]string{"pod-delete": "100", "pod-cpu-hog": "50"},
+			weightages:  map[string]int{"pod-delete": 1, "pod-cpu-hog": 3},
+			// (1*100 + 3*50) / 4
+			want: 62.5,
+		},
+		{
+			name:        "default weightage",
+			percentages: map[string]string{"pod-delete": "100", "pod-cpu-hog": "40"},
+			weightages:  map[string]int{"pod-delete": 30},
+			// (30*100 + 10*40) / 40
+			want: 85,
+		},
+		{
+			name:        "no weightages",
+			percentages: map[string]string{"pod-delete": "100", "pod-cpu-hog": "40"},
+			want:        70,
+		},
+		{
+			name:        "zero weightage",
+			percentages: map[string]string{"pod-delete": "100", "pod-cpu-hog": "0"},
+			weightages:  map[string]int{"pod-cpu-hog": 0},
+			want:        100,
+		},
+		{
+			name:        "all weightages zero",
+			percentages: map[string]string{"pod-delete": "100"},
+			weightages:  map[string]int{"pod-delete": 0},
+			want:        0,
+		},
+		{
+			name: "no experiments",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := workflowRunData{WorkflowRunID: "run-1", ExecutionData: testExecutionData(t, tt.percentages)}
+			run := data.toWorkflowRun(tt.weightages)
+			if math.Abs(run.ResiliencyScore-tt.want) > 1e-9 {
+				t.Errorf("ResiliencyScore = %v, want %v", run.ResiliencyScore, tt.want)
+			}
+			if len(run.Experiments) != len(tt.percentages) {
+				t.Errorf("%d experiments, want %d", len(run.Experiments), len(tt.percentages))
+			}
+			for _, e := range run.Experiments {
+				want, ok := tt.weightages[e.Name]
+				if !ok {
+					want = DefaultWeightage
+				}
+				if e.Weightage != want {
+					t.Errorf("weightage of %s = %d, want %d", e.Name, e.Weightage, want)
+				}
+			}
+		})
+	}
+}
+
+func TestListWorkflowRunsWeightages(t *testing.T) {
+	executionData := testExecutionData(t, map[string]string{"pod-delete": "100", "pod-cpu-hog": "20"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req util.GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var data interface{}
+		switch {
+		case strings.Contains(req.Query, "getWorkFlowRuns"):
+			data = map[string]interface{}{"getWorkFlowRuns": []map[string]interface{}{
+				{"workflow_id": "wf-1", "workflow_run_id": "run-1", "phase": "Succeeded", "execution_data": executionData},
+				{"workflow_id": "wf-2", "workflow_run_id": "run-2", "phase": "Succeeded", "execution_data": executionData},
+			}}
+		case strings.Contains(req.Query, "ListWorkflow"):
+			data = map[string]interface{}{"ListWorkflow": []map[string]interface{}{
+				{"workflow_id": "wf-1", "weightages": []map[string]interface{}{
+					{"experiment_name": "pod-delete", "weightage": 1},
+					{"experiment_name": "pod-cpu-hog", "weightage": 4},
+				}},
+			}}
+		default:
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	host, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &util.Session{
+		Credentials: util.Credentials{Host: host},
+		Token:       util.Token{AccessToken: "token", ExpiresIn: 3600},
+		IssuedAt:    time.Now(),
+	}
+	runs, err := ListWorkflowRuns("project-1", s)
+	if err != nil {
+		t.Fatalf("ListWorkflowRuns() error = %v", err)
+	}
+	want := map[string]float64{
+		// (1*100 + 4*20) / 5
+		"run-1": 36,
+		// Without weightages all the experiments weigh the same
+		"run-2": 60,
+	}
+	for _, run := range runs {
+		if math.Abs(run.ResiliencyScore-want[run.WorkflowRunID]) > 1e-9 {
+			t.Errorf("resiliency score of %s = %v, want %v", run.WorkflowRunID, run.ResiliencyScore, want[run.WorkflowRunID])
+		}
+	}
+	if len(runs) != len(want) {
+		t.Errorf("%d runs, want %d", len(runs), len(want))
+	}
+}
@@ -1,13 +1,13 @@
 package chaos
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
-	"github.com/go-resty/resty/v2"
-	ymlparser "gopkg.in/yaml.v2"
+	util "github.com/mayadata-io/cli-utils/pkg/common"
 	v1 "k8s.io/api/core/v1"
-	"log"
-	"net/url"
+	k8syaml "sigs.k8s.io/yaml"
+	"strings"
 )
 
 type ListPkgData struct {
@@ -31,13 +31,296 @@ type GenerateWorkflowInputs struct {
 	ProjectID      string
 	ChartName      string
 	ExperimentName *string
-	AccessToken    string
+	Session        *util.Session
 	FileType       *string
-	URL            *url.URL
 	WorkName       string
 	WorkNamespace  string
 	ClusterID      string
 	Packages       []*PackageData
+	// Stages run one after another, the experiments of a stage run
+	// in parallel. Each experiment of Packages runs in a stage of its
+	// own if no stages are given.
+	Stages []WorkflowStage
+	// Overrides of the hub defaults, by experiment name or by
+	// chart/experiment to tune the experiment of one chart only
+	Overrides map[string]ExperimentOverrides
+	// Probes added to the engines, keyed as the overrides
+	Probes map[string][]Probe
+	// Hub the charts are read from, the hub HubName of the
+	// project is used through the portal if it's nil
+	Hub ChartHub
+	// Cache of the manifests fetched from the portal, nothing
+	// is cached if it's nil
+	Cache *HubCache
+	// Concurrency is the number of manifests fetched at the same
+	// time, DefaultConcurrency is used if it's 0
+	Concurrency int
+}
+
+// hub returns the hub the charts are read from
+func (w GenerateWorkflowInputs) hub() ChartHub {
+	hub := w.Hub
+	if hub == nil {
+		hub = &GraphQLHub{Session: w.Session, ProjectID: w.ProjectID, HubName: w.HubName}
+	}
+	// The charts of a local hub are already on disk
+	if _, local := hub.(*LocalHub); local || w.Cache == nil {
+		return hub
+	}
+	name := w.ProjectID + "/" + w.HubName
+	if h, ok := hub.(*GraphQLHub); ok {
+		name = h.ProjectID + "/" + h.HubName
+	}
+	return NewCachedHub(hub, name, w.Cache)
+}
+
+// resolveCharts returns a copy of the stages with the chart of each
+// experiment set, the packages of the hub are searched for the
+// experiments whose chart isn't given
+func resolveCharts(hub ChartHub, stages []WorkflowStage, chartName string) ([]WorkflowStage, error) {
+	var packages staticHub
+	found := map[string]string{}
+	resolved := make([]WorkflowStage, len(stages))
+	for i, stage := range stages {
+		resolved[i] = WorkflowStage{Name: stage.Name, Experiments: make([]ExperimentRef, len(stage.Experiments))}
+		for j, ref := range stage.Experiments {
+			if ref.ChartName == "" {
+				ref.ChartName = chartName
+			}
+			if ref.ChartName == "" {
+				if chart, ok := found[ref.Experiment]; ok {
+					ref.ChartName = chart
+				} else {
+					var err error
+					if packages == nil {
+						if packages, err = hub.ListPackages(); err != nil {
+							return nil, err
+						}
+					}
+					if ref.ChartName, err = FindExperiment(packages, ref.Experiment); err != nil {
+						return nil, err
+					}
+					found[ref.Experiment] = ref.ChartName
+				}
+			}
+			resolved[i].Experiments[j] = ref
+		}
+	}
+	return resolved, nil
+}
+
+// templateNames returns the name of the template of each experiment.
+// It's the name of the experiment, prefixed with the chart if the
+// experiment of several charts runs in the workflow.
+func templateNames(stages []WorkflowStage) map[ExperimentRef]string {
+	charts := map[string]map[string]bool{}
+	for _, stage := range stages {
+		for _, ref := range stage.Experiments {
+			if charts[ref.Experiment] == nil {
+				charts[ref.Experiment] = map[string]bool{}
+			}
+			charts[ref.Experiment][ref.ChartName] = true
+		}
+	}
+	names := map[ExperimentRef]string{}
+	for _, stage := range stages {
+		for _, ref := range stage.Experiments {
+			names[ref] = ref.Experiment
+			if len(charts[ref.Experiment]) > 1 {
+				names[ref] = ref.ChartName + "-" + ref.Experiment
+			}
+		}
+	}
+	return names
+}
+
+// stepNames returns the names of the steps of each stage. A step is
+// named after its template, followed by the number of the stage if
+// the template runs in several stages, as Argo requires the names of
+// the steps to be unique.
+func stepNames(stages []WorkflowStage, templates map[ExperimentRef]string) [][]string {
+	runs := map[string]int{}
+	for _, stage := range stages {
+		for _, ref := range stage.Experiments {
+			runs[templates[ref]]++
+		}
+	}
+	names := make([][]string, len(stages))
+	for i, stage := range stages {
+		for _, ref := range stage.Experiments {
+			name := templates[ref]
+			if runs[name] > 1 {
+				name = fmt.Sprintf("%s-%d", name, i+1)
+			}
+			names[i] = append(names[i], name)
+		}
+	}
+	return names
+}
+
+// manifestKinds are the kinds of the manifests by file type
+var manifestKinds = map[string]string{
+	FileTypeExperiment: "ChaosExperiment",
+	FileTypeEngine:     "ChaosEngine",
+}
+
+// validateManifest checks that the manifest is a single
+// object of the kind expected for the file type
+func validateManifest(data, fileType string) error {
+	if strings.TrimSpace(data) == "" {
+		return fmt.Errorf("%w: empty manifest", ErrInvalidManifest)
+	}
+	manifest, err := k8syaml.YAMLToJSON([]byte(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	var object struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(manifest, &object); err != nil {
+		return fmt.Errorf("%w: not an object", ErrInvalidManifest)
+	}
+	if kind := manifestKinds[fileType]; object.Kind != kind {
+		return fmt.Errorf("%w: kind is %q instead of %s", ErrInvalidManifest, object.Kind, kind)
+	}
+	return nil
+}
+
+// engineRunLabel labels the engines with the uid of the workflow run
+// which created them, so that the run deletes its own engines only
+const engineRunLabel = "workflow_run_id"
+
+// nameEngine gives the engine a generated name starting with the given
+// prefix, as the engines of a chart share a name and an experiment may
+// run in several steps. The engine is labelled with the uid of the
+// workflow run, litmus-checker saves the name it was given.
+func nameEngine(engineYAML, prefix string) (string, error) {
+	data, err := k8syaml.YAMLToJSON([]byte(engineYAML))
+	if err != nil {
+		return "", fmt.Errorf("invalid chaos engine: %w", err)
+	}
+	var engine map[string]interface{}
+	if err := json.Unmarshal(data, &engine); err != nil || engine == nil {
+		return "", fmt.Errorf("invalid chaos engine: not an object")
+	}
+	metadata := child(engine, "metadata")
+	delete(metadata, "name")
+	metadata["generateName"] = prefix + "-"
+	child(metadata, "labels")[engineRunLabel] = "{{workflow.uid}}"
+
+	data, err = json.Marshal(engine)
+	if err != nil {
+		return "", err
+	}
+	out, err := k8syaml.JSONToYAML(data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// experimentManifests checks the fetched manifests and customizes the
+// engines. The manifests are returned ready to be embedded, or the
+// errors of all the experiments together as ExperimentErrors.
+func experimentManifests(wf_inputs GenerateWorkflowInputs, stages []WorkflowStage, fetched map[manifestKey]manifest) (map[manifestKey]string, error) {
+	var errs ExperimentErrors
+	manifests := map[manifestKey]string{}
+	for _, stage := range stages {
+		for _, ref := range stage.Experiments {
+			experiment := ref.Experiment
+			for _, fileType := range []string{FileTypeExperiment, FileTypeEngine} {
+				key := manifestKey{ref.ChartName, experiment, fileType}
+				if _, done := manifests[key]; done {
+					continue
+				}
+				m := fetched[key]
+				err := m.err
+				if err == nil {
+					err = validateManifest(m.data, fileType)
+				}
+				overrides, overridden := lookupOverrides(wf_inputs.Overrides, ref)
+				probes := lookupProbes(wf_inputs.Probes, ref)
+				if err == nil && fileType == FileTypeEngine && (overridden || len(probes) > 0) {
+					m.data, err = customizeEngine(m.data, experiment, overrides, probes)
+				}
+				if err != nil {
+					errs = append(errs, &ExperimentError{Experiment: experiment, FileType: fileType, Err: err})
+				}
+				manifests[key] = m.data
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return manifests, nil
+}
+
+// staticHub is a hub listing packages already fetched
+type staticHub []PackageData
+
+func (h staticHub) ListPackages() ([]PackageData, error) { return h, nil }
+
+func (h staticHub) GetYAML(chart, experiment, fileType string) (string, error) {
+	return "", fmt.Errorf("%s of %s/%s isn't available", fileType, chart, experiment)
+}
+
+// WorkflowStage is a set of experiments run at the same time
+type WorkflowStage struct {
+	Name        string
+	Experiments []ExperimentRef
+}
+
+// ExperimentRef refers to an experiment of a chart in the hub,
+// the ChartName of the inputs is used if it's empty
+type ExperimentRef struct {
+	ChartName  string
+	Experiment string
+}
+
+// keys returns the keys the overrides and the probes of the experiment
+// are looked up by, chart/experiment first and then the experiment name
+func (r ExperimentRef) keys() []string {
+	return []string{r.ChartName + "/" + r.Experiment, r.Experiment}
+}
+
+// stages returns the stages of the workflow, derived from the
+// packages if they aren't given
+func (w GenerateWorkflowInputs) stages() []WorkflowStage {
+	if len(w.Stages) > 0 {
+		return w.Stages
+	}
+	var stages []WorkflowStage
+	for _, pkg := range w.Packages {
+		for _, experiment := range pkg.Experiments {
+			stages = append(stages, WorkflowStage{
+				Name:        experiment,
+				Experiments: []ExperimentRef{{ChartName: pkg.ChartName, Experiment: experiment}},
+			})
+		}
+	}
+	return stages
+}
+
+// validateStages checks that the stages aren't empty and an
+// experiment doesn't run twice in a stage
+func validateStages(stages []WorkflowStage) error {
+	for i, stage := range stages {
+		if len(stage.Experiments) == 0 {
+			return fmt.Errorf("stage %d %s has no experiments", i+1, stage.Name)
+		}
+		seen := map[string]bool{}
+		for _, ref := range stage.Experiments {
+			if ref.Experiment == "" {
+				return fmt.Errorf("stage %d %s has an experiment without name", i+1, stage.Name)
+			}
+			if seen[ref.Experiment] {
+				return fmt.Errorf("experiment %s is repeated in stage %d %s", ref.Experiment, i+1, stage.Name)
+			}
+			seen[ref.Experiment] = true
+		}
+	}
+	return nil
 }
 
 type GetClusters struct {
@@ -58,33 +341,77 @@ type GetHubStatus struct {
 	} `json:"data"`
 }
 
-func GetYamlData(inputs GenerateWorkflowInputs) (YAMLData, error) {
-	client := resty.New()
+const getYamlDataQuery = `query getYAMLData($experimentInput: ExperimentInput!) {
+  getYAMLData(experimentInput: $experimentInput)
+}`
+
+const getClustersQuery = `query getCluster($projectID: String!) {
+  getCluster(project_id: $projectID) {
+    cluster_id
+    cluster_name
+  }
+}`
+
+const getHubStatusQuery = `query getHubStatus($projectID: String!) {
+  getHubStatus(projectID: $projectID) {
+    id
+    HubName
+  }
+}`
+
+const listPkgDataQuery = `query ListHubPkgData($projectID: String!, $hubID: String!) {
+  ListHubPkgData(projectID: $projectID, hubID: $hubID) {
+    Experiments
+    chartName
+  }
+}`
 
+func GetYamlData(inputs GenerateWorkflowInputs) (YAMLData, error) {
 	var yamlDataResponse YAMLData
-	gql_query := `{"query":"query {\n  getYAMLData(experimentInput: {\n    ProjectID: \"` + inputs.ProjectID + `\"\n    HubName: \"` + inputs.HubName + `\"\n    ChartName: \"` + inputs.ChartName + `\"\n    ExperimentName: \"` + *inputs.ExperimentName + `\"\n    FileType: \"` + *inputs.FileType + `\"\n    \n  })\n}"}`
-	response, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", inputs.AccessToken)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(gql_query).
-		SetResult(&yamlDataResponse).
-		Post(
-			fmt.Sprintf(
-				"%s/%s/api/graphql/query",
-				inputs.URL,
-				"chaos",
-			),
-		)
-
-	if err != nil || !response.IsSuccess() {
-		return YAMLData{}, err
+	client := util.NewGraphQLClient(inputs.Session, "chaos")
+	err := client.Do(getYamlDataQuery, map[string]interface{}{
+		"experimentInput": map[string]interface{}{
+			"ProjectID":      inputs.ProjectID,
+			"HubName":        inputs.HubName,
+			"ChartName":      inputs.ChartName,
+			"ExperimentName": *inputs.ExperimentName,
+			"FileType":       *inputs.FileType,
+		},
+	}, &yamlDataResponse.Data)
+	if err != nil {
+		return YAMLData{}, fmt.Errorf("fetching %s of %s/%s failed: %w", *inputs.FileType, inputs.ChartName, *inputs.ExperimentName, err)
 	}
 
 	return yamlDataResponse, nil
 }
 
 func GenerateWorkflow(wf_inputs GenerateWorkflowInputs) ([]byte, error) {
+	yaml, err := buildWorkflow(wf_inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlByte, err := marshalManifest(yaml)
+	if err != nil {
+		return nil, err
+	}
+
+	return yamlByte, nil
+}
+
+// marshalManifest converts the object to YAML following its json
+// tags, so that the field names are the ones kubernetes expects
+func marshalManifest(obj interface{}) ([]byte, error) {
+	return k8syaml.Marshal(obj)
+}
+
+// buildWorkflow builds the workflow installing the experiments and
+// running them stage by stage
+func buildWorkflow(wf_inputs GenerateWorkflowInputs) (v1alpha1.Workflow, error) {
+	stages := wf_inputs.stages()
+	if err := validateStages(stages); err != nil {
+		return v1alpha1.Workflow{}, err
+	}
 
 	var yaml v1alpha1.Workflow
 
@@ -131,72 +458,103 @@ func GenerateWorkflow(wf_inputs GenerateWorkflowInputs) ([]byte, error) {
 		Args:    []string{""},
 	}
 
+	// The engines created by the run are deleted by their label
 	revert_chaos.Name = "revert-chaos"
 	revert_chaos.Container = &v1.Container{
 		Image:   "lachlanevenson/k8s-kubectl",
 		Command: []string{"sh", "-c"},
-		Args:    []string{"kubectl delete chaosengine "},
+		Args:    []string{"kubectl delete chaosengine -l " + engineRunLabel + "={{workflow.uid}} "},
 	}
 
-	for _, pkg := range wf_inputs.Packages {
+	// The manifests of all the experiments are fetched up front
+	hub := wf_inputs.hub()
+	stages, err := resolveCharts(hub, stages, wf_inputs.ChartName)
+	if err != nil {
+		return v1alpha1.Workflow{}, err
+	}
+	// The charts are known now, the overrides and the probes
+	// may be given for the experiment of a chart
+	if err := validateOverrides(wf_inputs.Overrides, stages); err != nil {
+		return v1alpha1.Workflow{}, err
+	}
+	if err := validateProbes(wf_inputs.Probes, stages); err != nil {
+		return v1alpha1.Workflow{}, err
+	}
+	var keys []manifestKey
+	seen := map[ExperimentRef]bool{}
+	for _, stage := range stages {
+		for _, ref := range stage.Experiments {
+			if !seen[ref] {
+				seen[ref] = true
+				keys = append(keys,
+					manifestKey{ref.ChartName, ref.Experiment, FileTypeExperiment},
+					manifestKey{ref.ChartName, ref.Experiment, FileTypeEngine})
+			}
+		}
+	}
+	manifests, err := experimentManifests(wf_inputs, stages, fetchManifests(hub, keys, wf_inputs.Concurrency))
+	if err != nil {
+		return v1alpha1.Workflow{}, err
+	}
 
-		for _, experiment := range pkg.Experiments {
+	templates := templateNames(stages)
+	steps := stepNames(stages, templates)
+	installed := map[ExperimentRef]bool{}
+	for i, stage := range stages {
 
-			wf_inputs.ExperimentName = &experiment
+		var group []v1alpha1.WorkflowStep
+		for j, ref := range stage.Experiments {
+			group = append(group, v1alpha1.WorkflowStep{
+				Name:     steps[i][j],
+				Template: templates[ref],
+			})
+		}
+		custom_chaos.Steps = append(custom_chaos.Steps, v1alpha1.ParallelSteps{Steps: group})
 
-			custom_chaos.Steps = append(custom_chaos.Steps, v1alpha1.ParallelSteps{Steps: []v1alpha1.WorkflowStep{
-				{
-					Name:     experiment,
-					Template: experiment,
-				},
-			}})
-			//
-			var file_type = "experiment"
-			wf_inputs.FileType = &file_type
-			yamlData, err := GetYamlData(wf_inputs)
-			if err != nil {
-				log.Print(err)
+		for _, ref := range stage.Experiments {
+
+			// An experiment run in several stages is installed once
+			if installed[ref] {
+				continue
 			}
+			installed[ref] = true
 
+			chart, experiment, name := ref.ChartName, ref.Experiment, templates[ref]
+			//
 			install_experiments.Inputs.Artifacts = append(install_experiments.Inputs.Artifacts,
 				v1alpha1.Artifact{
-					Name: experiment,
-					Path: "/tmp/" + experiment + ".yaml",
+					Name: name,
+					Path: "/tmp/" + name + ".yaml",
 					ArtifactLocation: v1alpha1.ArtifactLocation{
 						Raw: &v1alpha1.RawArtifact{
-							Data: fmt.Sprint(yamlData.Data.GetYAMLData),
+							Data: manifests[manifestKey{chart, experiment, FileTypeExperiment}],
 						},
 					},
 				})
 
-			install_experiments.Container.Args[0] += "kubectl apply -f /tmp/" + experiment + ".yaml" + "-n {{workflow.parameters.adminModeNamespace}} | "
-
-			revert_chaos.Container.Args[0] += experiment + " "
-
-			file_type = "engine"
-			wf_inputs.FileType = &file_type
+			install_experiments.Container.Args[0] += "kubectl apply -f /tmp/" + name + ".yaml" + " -n {{workflow.parameters.adminModeNamespace}} | "
 
-			yamlData, err = GetYamlData(wf_inputs)
+			engineYAML, err := nameEngine(manifests[manifestKey{chart, experiment, FileTypeEngine}], name)
 			if err != nil {
-				log.Print(err)
+				return v1alpha1.Workflow{}, &ExperimentError{Experiment: experiment, FileType: FileTypeEngine, Err: err}
 			}
 
 			var engine v1alpha1.Template
-			engine.Name = experiment
+			engine.Name = name
 			engine.Container = &v1.Container{
 				Args: []string{
-					`-file=/tmp/chaosengine-` + experiment + `.yaml`,
+					`-file=/tmp/chaosengine-` + name + `.yaml`,
 					"-saveName=/tmp/engine-name",
 				},
 				Image: "litmuschaos/litmus-checker:latest",
 			}
 
 			engine.Inputs.Artifacts = append(engine.Inputs.Artifacts, v1alpha1.Artifact{
-				Name: experiment,
-				Path: "/tmp/chaosengine-" + experiment + ".yaml",
+				Name: name,
+				Path: "/tmp/chaosengine-" + name + ".yaml",
 				ArtifactLocation: v1alpha1.ArtifactLocation{
 					Raw: &v1alpha1.RawArtifact{
-						Data: fmt.Sprint(yamlData.Data.GetYAMLData),
+						Data: engineYAML,
 					},
 				},
 			})
@@ -226,91 +584,44 @@ func GenerateWorkflow(wf_inputs GenerateWorkflowInputs) ([]byte, error) {
 	revert_chaos.Container.Args[0] += "-n {{workflow.parameters.adminModeNamespace}}"
 	yaml.Spec.Templates = append(yaml.Spec.Templates, revert_chaos)
 
-	yamlByte, err := ymlparser.Marshal(yaml)
-	if err != nil {
-		return nil, err
-	}
-
-	return yamlByte, nil
+	return yaml, nil
 }
 
-func GetClustersQuery(project_id string, access_token string, url *url.URL) (GetClusters, error) {
-	client := resty.New()
-
+func GetClustersQuery(project_id string, s *util.Session) (GetClusters, error) {
 	var getClusters GetClusters
-	gql_query := `{"query":"query {\n  getCluster(project_id: \"` + project_id + `\"){\n    cluster_id\n cluster_name\n  }\n}"}`
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", access_token)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(gql_query).
-		// SetResult automatic unmarshalling for the request,
-		// if response status code is between 200 and 299
-		SetResult(&getClusters).
-		Post(
-			fmt.Sprintf(
-				"%s/%s/api/graphql/query",
-				url,
-				"chaos",
-			),
-		)
-	if err != nil || !resp.IsSuccess() {
+	client := util.NewGraphQLClient(s, "chaos")
+	err := client.Do(getClustersQuery, map[string]interface{}{
+		"projectID": project_id,
+	}, &getClusters.Data)
+	if err != nil {
 		return GetClusters{}, err
 	}
 
 	return getClusters, nil
 }
 
-func GetHubStatusQuery(project_id string, access_token string, url *url.URL) (GetHubStatus, error) {
-	client := resty.New()
-
+func GetHubStatusQuery(project_id string, s *util.Session) (GetHubStatus, error) {
 	var getHubStatus GetHubStatus
-	gql_query := `{"query":"query {\n  getHubStatus(projectID: \"` + project_id + `\"){\n    id\n HubName \n  }\n}"}`
-	response, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", access_token)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(gql_query).
-		// SetResult automatic unmarshalling for the request,
-		// if response status code is between 200 and 299
-		SetResult(&getHubStatus).
-		Post(
-			fmt.Sprintf(
-				"%s/%s/api/graphql/query",
-				url,
-				"chaos",
-			),
-		)
-	if err != nil || !response.IsSuccess() {
-		return GetHubStatus{}, nil
+	client := util.NewGraphQLClient(s, "chaos")
+	err := client.Do(getHubStatusQuery, map[string]interface{}{
+		"projectID": project_id,
+	}, &getHubStatus.Data)
+	if err != nil {
+		return GetHubStatus{}, fmt.Errorf("fetching the hubs of project %s failed: %w", project_id, err)
 	}
 
 	return getHubStatus, nil
 }
 
-func ListPkgDataQuery(project_id string, hub_id string, access_token string, url *url.URL) (ListPkgData, error) {
+func ListPkgDataQuery(project_id string, hub_id string, s *util.Session) (ListPkgData, error) {
 	var pkgdata ListPkgData
-
-	client := resty.New()
-
-	gql_query := `{"query":"query {\n  ListHubPkgData(projectID: \"` + project_id + `\", hubID: \"` + hub_id + `\"){\n    Experiments\n    chartName\n  }\n}"}`
-	response, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", access_token)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(gql_query).
-		// SetResult automatic unmarshalling for the request,
-		// if response status code is between 200 and 299
-		SetResult(&pkgdata).
-		Post(
-			fmt.Sprintf(
-				"%s/%s/api/graphql/query",
-				url,
-				"chaos",
-			),
-		)
-	if err != nil || !response.IsSuccess() {
-		log.Print(err)
+	client := util.NewGraphQLClient(s, "chaos")
+	err := client.Do(listPkgDataQuery, map[string]interface{}{
+		"projectID": project_id,
+		"hubID":     hub_id,
+	}, &pkgdata.Data)
+	if err != nil {
+		return ListPkgData{}, fmt.Errorf("listing the packages of hub %s failed: %w", hub_id, err)
 	}
 
 	return pkgdata, nil
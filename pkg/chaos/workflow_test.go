@@ -0,0 +1,266 @@
+package chaos
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+var (
+	argoFieldName    = regexp.MustCompile(`^[a-zA-Z0-9][-a-zA-Z0-9]*$`)
+	argoArtifactName = regexp.MustCompile(`^[-a-zA-Z0-9_]+$`)
+)
+
+// validateArgo checks the rules of the Argo validator which apply to
+// the generated workflows. The validate package of Argo can't be built
+// here, its clientset requires an older client-go.
+func validateArgo(t *testing.T, wf v1alpha1.Workflow) {
+	t.Helper()
+	templates := map[string]v1alpha1.Template{}
+	for i, tmpl := range wf.Spec.Templates {
+		if len(tmpl.Name) > 128 || !argoFieldName.MatchString(tmpl.Name) {
+			t.Errorf("spec.templates[%d].name %q is invalid", i, tmpl.Name)
+		}
+		if _, ok := templates[tmpl.Name]; ok {
+			t.Errorf("spec.templates[%d].name %q is not unique", i, tmpl.Name)
+		}
+		templates[tmpl.Name] = tmpl
+
+		artifacts := map[string]bool{}
+		for _, art := range tmpl.Inputs.Artifacts {
+			if !argoArtifactName.MatchString(art.Name) {
+				t.Errorf("templates.%s.inputs.artifacts.name %q is invalid", tmpl.Name, art.Name)
+			}
+			if artifacts[art.Name] {
+				t.Errorf("templates.%s.inputs.artifacts.name %q is not unique", tmpl.Name, art.Name)
+			}
+			artifacts[art.Name] = true
+		}
+	}
+	if _, ok := templates[wf.Spec.Entrypoint]; !ok {
+		t.Errorf("spec.entrypoint template %q not found", wf.Spec.Entrypoint)
+	}
+	for _, tmpl := range wf.Spec.Templates {
+		steps := map[string]bool{}
+		for i, group := range tmpl.Steps {
+			for _, step := range group.Steps {
+				if len(step.Name) > 128 || !argoFieldName.MatchString(step.Name) {
+					t.Errorf("templates.%s.steps[%d].name %q is invalid", tmpl.Name, i, step.Name)
+				}
+				if steps[step.Name] {
+					t.Errorf("templates.%s.steps[%d].name %q is not unique", tmpl.Name, i, step.Name)
+				}
+				steps[step.Name] = true
+				if _, ok := templates[step.Template]; !ok {
+					t.Errorf("templates.%s.steps[%d].%s template %q not found", tmpl.Name, i, step.Name, step.Template)
+				}
+			}
+		}
+	}
+}
+
+// stepNamesOf returns the names of the steps of the stages,
+// without the install and revert steps
+func stepNamesOf(wf v1alpha1.Workflow) [][]string {
+	var names [][]string
+	groups := wf.Spec.Templates[0].Steps
+	for _, group := range groups[1 : len(groups)-1] {
+		var stage []string
+		for _, step := range group.Steps {
+			stage = append(stage, step.Name+"="+step.Template)
+		}
+		names = append(names, stage)
+	}
+	return names
+}
+
+func TestBuildWorkflow(t *testing.T) {
+	hub := newTestHub("generic/pod-delete", "generic/pod-cpu-hog", "generic/node-drain", "kube-aws/node-drain")
+	tests := []struct {
+		name          string
+		inputs        GenerateWorkflowInputs
+		wantSteps     [][]string
+		wantTemplates []string
+	}{
+		{
+			name: "one stage per experiment of the packages",
+			inputs: GenerateWorkflowInputs{Packages: []*PackageData{
+				{ChartName: "generic", Experiments: []string{"pod-delete", "pod-cpu-hog"}},
+			}},
+			wantSteps:     [][]string{{"pod-delete=pod-delete"}, {"pod-cpu-hog=pod-cpu-hog"}},
+			wantTemplates: []string{"pod-delete", "pod-cpu-hog"},
+		},
+		{
+			name: "experiment in several stages",
+			inputs: GenerateWorkflowInputs{ChartName: "generic", Stages: []WorkflowStage{
+				{Name: "warm-up", Experiments: []ExperimentRef{{Experiment: "pod-delete"}}},
+				{Name: "load", Experiments: []ExperimentRef{{Experiment: "pod-cpu-hog"}, {Experiment: "pod-delete"}}},
+				{Name: "again", Experiments: []ExperimentRef{{Experiment: "pod-delete"}}},
+			}},
+			wantSteps: [][]string{
+				{"pod-delete-1=pod-delete"},
+				{"pod-cpu-hog=pod-cpu-hog", "pod-delete-2=pod-delete"},
+				{"pod-delete-3=pod-delete"},
+			},
+			wantTemplates: []string{"pod-delete", "pod-cpu-hog"},
+		},
+		{
+			name: "experiment of several charts",
+			inputs: GenerateWorkflowInputs{Stages: []WorkflowStage{
+				{Name: "generic", Experiments: []ExperimentRef{{ChartName: "generic", Experiment: "node-drain"}, {Experiment: "pod-delete"}}},
+				{Name: "aws", Experiments: []ExperimentRef{{ChartName: "kube-aws", Experiment: "node-drain"}}},
+			}},
+			wantSteps: [][]string{
+				{"generic-node-drain=generic-node-drain", "pod-delete=pod-delete"},
+				{"kube-aws-node-drain=kube-aws-node-drain"},
+			},
+			wantTemplates: []string{"generic-node-drain", "pod-delete", "kube-aws-node-drain"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.inputs.Hub = hub
+			tt.inputs.WorkName = "chaos"
+			tt.inputs.WorkNamespace = "litmus"
+			wf, err := buildWorkflow(tt.inputs)
+			if err != nil {
+				t.Fatalf("buildWorkflow() error = %v", err)
+			}
+			validateArgo(t, wf)
+
+			if got := stepNamesOf(wf); !reflect.DeepEqual(got, tt.wantSteps) {
+				t.Errorf("steps = %v, want %v", got, tt.wantSteps)
+			}
+			// The templates of the experiments are between the install
+			// and the revert templates, one per experiment
+			var engines []string
+			for _, tmpl := range wf.Spec.Templates[2 : len(wf.Spec.Templates)-1] {
+				engines = append(engines, tmpl.Name)
+			}
+			if !reflect.DeepEqual(engines, tt.wantTemplates) {
+				t.Errorf("templates = %v, want %v", engines, tt.wantTemplates)
+			}
+			install := wf.Spec.Templates[1]
+			if len(install.Inputs.Artifacts) != len(tt.wantTemplates) {
+				t.Errorf("%d experiments installed, want %d", len(install.Inputs.Artifacts), len(tt.wantTemplates))
+			}
+
+			// The marshalled manifest is what Argo receives
+			data, err := marshalManifest(wf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var parsed v1alpha1.Workflow
+			if err := k8syaml.UnmarshalStrict(data, &parsed); err != nil {
+				t.Fatalf("invalid manifest: %v", err)
+			}
+			validateArgo(t, parsed)
+		})
+	}
+}
+
+func TestBuildWorkflowUsesTheManifestsOfTheChart(t *testing.T) {
+	hub := newTestHub("generic/node-drain", "kube-aws/node-drain")
+	hub["kube-aws"]["node-drain"][FileTypeEngine] = strings.Replace(testEngineYAML("node-drain"), "app=nginx", "app=aws", 1)
+	wf, err := buildWorkflow(GenerateWorkflowInputs{Hub: hub, Stages: []WorkflowStage{
+		{Name: "drain", Experiments: []ExperimentRef{{ChartName: "generic", Experiment: "node-drain"}}},
+		{Name: "aws", Experiments: []ExperimentRef{{ChartName: "kube-aws", Experiment: "node-drain"}}},
+	}})
+	if err != nil {
+		t.Fatalf("buildWorkflow() error = %v", err)
+	}
+	engines := map[string]string{}
+	for _, tmpl := range wf.Spec.Templates {
+		if len(tmpl.Inputs.Artifacts) == 1 && tmpl.Container != nil && strings.HasPrefix(tmpl.Container.Image, "litmuschaos/litmus-checker") {
+			engines[tmpl.Name] = tmpl.Inputs.Artifacts[0].Raw.Data
+		}
+	}
+	if !strings.Contains(engines["generic-node-drain"], "app=nginx") {
+		t.Errorf("engine of generic-node-drain isn't the one of the generic chart:\n%s", engines["generic-node-drain"])
+	}
+	if !strings.Contains(engines["kube-aws-node-drain"], "app=aws") {
+		t.Errorf("engine of kube-aws-node-drain isn't the one of the kube-aws chart:\n%s", engines["kube-aws-node-drain"])
+	}
+}
+
+func TestBuildWorkflowNamesTheEngines(t *testing.T) {
+	hub := newTestHub("generic/pod-delete", "generic/pod-cpu-hog")
+	wf, err := buildWorkflow(GenerateWorkflowInputs{Hub: hub, ChartName: "generic", Stages: []WorkflowStage{
+		{Name: "parallel", Experiments: []ExperimentRef{{Experiment: "pod-delete"}, {Experiment: "pod-cpu-hog"}}},
+		{Name: "again", Experiments: []ExperimentRef{{Experiment: "pod-delete"}}},
+	}})
+	if err != nil {
+		t.Fatalf("buildWorkflow() error = %v", err)
+	}
+	for _, tmpl := range wf.Spec.Templates[2 : len(wf.Spec.Templates)-1] {
+		var engine struct {
+			Metadata struct {
+				Name         string            `json:"name"`
+				GenerateName string            `json:"generateName"`
+				Labels       map[string]string `json:"labels"`
+			} `json:"metadata"`
+		}
+		if err := k8syaml.Unmarshal([]byte(tmpl.Inputs.Artifacts[0].Raw.Data), &engine); err != nil {
+			t.Fatal(err)
+		}
+		// The engines of the hub are all named nginx-chaos
+		if engine.Metadata.Name != "" {
+			t.Errorf("engine of %s is named %q, want a generated name", tmpl.Name, engine.Metadata.Name)
+		}
+		if want := tmpl.Name + "-"; engine.Metadata.GenerateName != want {
+			t.Errorf("generateName of the engine of %s = %q, want %q", tmpl.Name, engine.Metadata.GenerateName, want)
+		}
+		if got := engine.Metadata.Labels[engineRunLabel]; got != "{{workflow.uid}}" {
+			t.Errorf("%s label of the engine of %s = %q, want the workflow uid", engineRunLabel, tmpl.Name, got)
+		}
+	}
+
+	revert := wf.Spec.Templates[len(wf.Spec.Templates)-1]
+	want := "kubectl delete chaosengine -l workflow_run_id={{workflow.uid}} -n {{workflow.parameters.adminModeNamespace}}"
+	if got := revert.Container.Args[0]; got != want {
+		t.Errorf("revert-chaos runs %q, want %q", got, want)
+	}
+}
+
+func TestBuildWorkflowErrors(t *testing.T) {
+	hub := newTestHub("generic/pod-delete", "generic/node-drain", "kube-aws/node-drain")
+	hub["generic"]["pod-delete"][FileTypeEngine] = "kind: ChaosExperiment\n"
+	tests := []struct {
+		name    string
+		stages  []WorkflowStage
+		wantErr string
+	}{
+		{
+			name:    "empty stage",
+			stages:  []WorkflowStage{{Name: "empty"}},
+			wantErr: "has no experiments",
+		},
+		{
+			name:    "experiment repeated in a stage",
+			stages:  []WorkflowStage{{Name: "twice", Experiments: []ExperimentRef{{ChartName: "generic", Experiment: "node-drain"}, {ChartName: "generic", Experiment: "node-drain"}}}},
+			wantErr: "is repeated in stage 1",
+		},
+		{
+			name:    "ambiguous chart",
+			stages:  []WorkflowStage{{Name: "drain", Experiments: []ExperimentRef{{Experiment: "node-drain"}}}},
+			wantErr: "is in several charts",
+		},
+		{
+			name:    "invalid engine",
+			stages:  []WorkflowStage{{Name: "pods", Experiments: []ExperimentRef{{Experiment: "pod-delete"}}}},
+			wantErr: "pod-delete engine",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildWorkflow(GenerateWorkflowInputs{Hub: hub, Stages: tt.stages})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("buildWorkflow() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
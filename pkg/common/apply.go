@@ -2,15 +2,42 @@ package common
 
 import (
 	"fmt"
-	"os/exec"
+
+	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
 )
 
-func ApplyYaml(token string, cred Credentials, yamlPath string) (output string, err error) {
-	path := fmt.Sprintf("%s/%s/%s.yaml", cred.Host, yamlPath, token)
-	args := []string{"kubectl", "apply", "-f", path}
-	stdout, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+// FetchManifest downloads the agent registration manifest
+// for the given registration token
+func FetchManifest(s *Session, token string, yamlPath string) ([]byte, error) {
+	accessToken, err := s.AccessToken()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.R().
+		SetHeader("Authorization", accessToken).
+		Get(
+			fmt.Sprintf(
+				"%s/%s/%s.yaml",
+				s.Credentials.Host,
+				yamlPath,
+				token,
+			),
+		)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, &HTTPError{StatusCode: resp.StatusCode(), Status: resp.Status()}
+	}
+	return resp.Body(), nil
+}
+
+// ApplyYaml downloads the agent registration manifest and applies
+// it to the cluster, the result of applying each object is returned
+func ApplyYaml(s *Session, token string, yamlPath string) ([]k8s.ApplyResult, error) {
+	manifest, err := FetchManifest(s, token, yamlPath)
 	if err != nil {
-		err = fmt.Errorf("Error: %v", err)
+		return nil, fmt.Errorf("failed to download registration yaml: %w", err)
 	}
-	return string(stdout), err
+	return k8s.ApplyManifest(manifest)
 }
@@ -0,0 +1,217 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mayadata-io/cli-utils/pkg/constants"
+	ymlparser "gopkg.in/yaml.v2"
+)
+
+var (
+	// ErrContextNotFound is returned when no context has the given name
+	ErrContextNotFound = errors.New("context not found")
+
+	// ErrContextExists is returned when adding a context whose name is taken
+	ErrContextExists = errors.New("context already exists")
+
+	// ErrNoCurrentContext is returned when no context is in use
+	ErrNoCurrentContext = errors.New("no current context is set")
+)
+
+// Context holds the details of an account on a Kubera Enterprise portal
+type Context struct {
+	Name             string    `yaml:"name"`
+	Host             string    `yaml:"host"`
+	Username         string    `yaml:"username"`
+	AccessToken      string    `yaml:"accessToken,omitempty"`
+	ExpiresIn        int       `yaml:"expiresIn,omitempty"`
+	IssuedAt         time.Time `yaml:"issuedAt,omitempty"`
+	DefaultProject   string    `yaml:"defaultProject,omitempty"`
+	DefaultNamespace string    `yaml:"defaultNamespace,omitempty"`
+}
+
+// Config holds the contexts stored in the config file
+type Config struct {
+	CurrentContext string    `yaml:"currentContext"`
+	Contexts       []Context `yaml:"contexts"`
+
+	path string
+}
+
+// DefaultConfigPath returns the path of the config file
+// inside the config directory of the user
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, constants.ConfigDirName, constants.ConfigFileName), nil
+}
+
+// LoadConfig reads the config file at the given path, an empty
+// config is returned if the file doesn't exist yet
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ymlparser.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Save writes the config to its file, the file is readable
+// and writable only by the user
+func (c *Config) Save() error {
+	data, err := ymlparser.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a failed write
+	// doesn't leave a truncated config behind
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Path returns the path of the config file
+func (c *Config) Path() string {
+	return c.path
+}
+
+// AddContext adds the given context, the first context
+// added is used as the current context
+func (c *Config) AddContext(ctx Context) error {
+	if ctx.Name == "" {
+		return errors.New("context name cannot be empty")
+	}
+	if _, err := url.Parse(ctx.Host); err != nil || ctx.Host == "" {
+		return fmt.Errorf("invalid host %q for context %s", ctx.Host, ctx.Name)
+	}
+	if _, err := c.GetContext(ctx.Name); err == nil {
+		return fmt.Errorf("%w: %s", ErrContextExists, ctx.Name)
+	}
+	c.Contexts = append(c.Contexts, ctx)
+	if c.CurrentContext == "" {
+		c.CurrentContext = ctx.Name
+	}
+	return nil
+}
+
+// ListContexts returns all the contexts
+func (c *Config) ListContexts() []Context {
+	return c.Contexts
+}
+
+// GetContext returns the context with the given name
+func (c *Config) GetContext(name string) (*Context, error) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrContextNotFound, name)
+}
+
+// UseContext switches the current context to the given one
+func (c *Config) UseContext(name string) error {
+	if _, err := c.GetContext(name); err != nil {
+		return err
+	}
+	c.CurrentContext = name
+	return nil
+}
+
+// DeleteContext removes the context with the given name, the
+// current context is unset if it's the one being removed
+func (c *Config) DeleteContext(name string) error {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			c.Contexts = append(c.Contexts[:i], c.Contexts[i+1:]...)
+			if c.CurrentContext == name {
+				c.CurrentContext = ""
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrContextNotFound, name)
+}
+
+// Current returns the context in use
+func (c *Config) Current() (*Context, error) {
+	if c.CurrentContext == "" {
+		return nil, ErrNoCurrentContext
+	}
+	return c.GetContext(c.CurrentContext)
+}
+
+// Credentials returns the credentials of the context
+// along with the given password
+func (ctx *Context) Credentials(password []byte) (Credentials, error) {
+	host, err := url.Parse(ctx.Host)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return Credentials{
+		Host:     host,
+		Username: ctx.Username,
+		Password: password,
+	}, nil
+}
+
+// Session returns a session using the token cached in the context.
+// ErrSessionExpired is returned if the token has expired and no
+// password is given to fetch a new one.
+func (ctx *Context) Session(password []byte) (*Session, error) {
+	c, err := ctx.Credentials(password)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{
+		Credentials: c,
+		Token: Token{
+			AccessToken: ctx.AccessToken,
+			ExpiresIn:   ctx.ExpiresIn,
+		},
+		IssuedAt: ctx.IssuedAt,
+	}
+	if _, err := s.AccessToken(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetToken caches the token of the given session in the context
+func (ctx *Context) SetToken(s *Session) {
+	ctx.AccessToken = s.Token.AccessToken
+	ctx.ExpiresIn = s.Token.ExpiresIn
+	ctx.IssuedAt = s.IssuedAt
+}
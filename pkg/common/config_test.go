@@ -0,0 +1,195 @@
+package common
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func tempConfigPath(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "kubera", "config.yaml")
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	path := tempConfigPath(t)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(config.Contexts) != 0 || config.CurrentContext != "" {
+		t.Errorf("LoadConfig() = %+v, want an empty config", config)
+	}
+	if config.Path() != path {
+		t.Errorf("Path() = %s, want %s", config.Path(), path)
+	}
+}
+
+func TestLoadConfigInvalidFile(t *testing.T) {
+	path := tempConfigPath(t)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("contexts: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("LoadConfig() error = nil, want an error for invalid YAML")
+	}
+}
+
+func TestAddContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     Context
+		wantErr error
+		invalid bool
+	}{
+		{name: "valid", ctx: Context{Name: "dev", Host: "https://dev.example.com"}},
+		{name: "duplicate", ctx: Context{Name: "prod", Host: "https://prod.example.com"}, wantErr: ErrContextExists},
+		{name: "empty name", ctx: Context{Host: "https://dev.example.com"}, invalid: true},
+		{name: "empty host", ctx: Context{Name: "dev"}, invalid: true},
+		{name: "invalid host", ctx: Context{Name: "dev", Host: "://bad"}, invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{}
+			if err := config.AddContext(Context{Name: "prod", Host: "https://prod.example.com"}); err != nil {
+				t.Fatal(err)
+			}
+			err := config.AddContext(tt.ctx)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("AddContext() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.invalid:
+				if err == nil {
+					t.Error("AddContext() error = nil, want an error")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("AddContext() error = %v", err)
+				}
+				if _, err := config.GetContext(tt.ctx.Name); err != nil {
+					t.Errorf("GetContext() error = %v", err)
+				}
+				// The first context added stays the current one
+				if config.CurrentContext != "prod" {
+					t.Errorf("CurrentContext = %s, want prod", config.CurrentContext)
+				}
+			}
+		})
+	}
+}
+
+func TestUseContext(t *testing.T) {
+	config := &Config{}
+	for _, name := range []string{"prod", "dev"} {
+		if err := config.AddContext(Context{Name: name, Host: "https://" + name + ".example.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := config.UseContext("dev"); err != nil {
+		t.Fatalf("UseContext() error = %v", err)
+	}
+	current, err := config.Current()
+	if err != nil || current.Name != "dev" {
+		t.Errorf("Current() = %v, %v, want dev", current, err)
+	}
+	if err := config.UseContext("staging"); !errors.Is(err, ErrContextNotFound) {
+		t.Errorf("UseContext() error = %v, want %v", err, ErrContextNotFound)
+	}
+	if config.CurrentContext != "dev" {
+		t.Errorf("CurrentContext = %s after a failed UseContext, want dev", config.CurrentContext)
+	}
+}
+
+func TestDeleteContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		delete      string
+		wantErr     error
+		wantCurrent string
+		wantLeft    int
+	}{
+		{name: "current", delete: "prod", wantCurrent: "", wantLeft: 1},
+		{name: "other", delete: "dev", wantCurrent: "prod", wantLeft: 1},
+		{name: "missing", delete: "staging", wantErr: ErrContextNotFound, wantCurrent: "prod", wantLeft: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{}
+			for _, name := range []string{"prod", "dev"} {
+				if err := config.AddContext(Context{Name: name, Host: "https://" + name + ".example.com"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := config.DeleteContext(tt.delete); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if config.CurrentContext != tt.wantCurrent {
+				t.Errorf("CurrentContext = %q, want %q", config.CurrentContext, tt.wantCurrent)
+			}
+			if len(config.Contexts) != tt.wantLeft {
+				t.Errorf("%d contexts left, want %d", len(config.Contexts), tt.wantLeft)
+			}
+			if tt.wantCurrent == "" {
+				if _, err := config.Current(); !errors.Is(err, ErrNoCurrentContext) {
+					t.Errorf("Current() error = %v, want %v", err, ErrNoCurrentContext)
+				}
+			}
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := tempConfigPath(t)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := Context{Name: "prod", Host: "https://prod.example.com", Username: "admin", AccessToken: "secret", DefaultProject: "p1"}
+	if err := config.AddContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("config file not written: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("config file mode = %v, want 0600", info.Mode().Perm())
+	}
+	// No temporary file is left behind
+	entries, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d files in the config directory, want 1", len(entries))
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	got, err := loaded.Current()
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+	if *got != ctx {
+		t.Errorf("Current() = %+v, want %+v", *got, ctx)
+	}
+}
@@ -1,6 +1,24 @@
 package common
 
+import "errors"
+
 type Errors struct {
 	Message string   `json:"message"`
 	Path    []string `json:"path"`
 }
+
+var (
+	// ErrLoginFailed is returned when a token can't be fetched
+	// for the given credentials
+	ErrLoginFailed = errors.New("login failed")
+
+	// ErrEmptyPassword is returned when no password is entered
+	ErrEmptyPassword = errors.New("password cannot be empty")
+
+	// ErrSessionExpired is returned when the token of a session has
+	// expired and can't be refreshed without a password
+	ErrSessionExpired = errors.New("session expired, please login again")
+
+	// ErrAborted is returned when the user declines to continue
+	ErrAborted = errors.New("aborted by user")
+)
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// GraphQLRequest is the body of a GraphQL operation
+type GraphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLResponse is the standard envelope of a GraphQL response
+type GraphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []Errors        `json:"errors"`
+}
+
+// GraphQLError holds the errors returned by the GraphQL server
+type GraphQLError struct {
+	Errors []Errors
+}
+
+func (e *GraphQLError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		if len(err.Path) > 0 {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", strings.Join(err.Path, "."), err.Message))
+		} else {
+			msgs = append(msgs, err.Message)
+		}
+	}
+	return "graphql: " + strings.Join(msgs, "; ")
+}
+
+// HTTPError is returned when the server responds with a non 2xx status
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("request failed: %s", e.Status)
+}
+
+// httpClient is shared by all the requests, so that the
+// connections to the server are reused
+var httpClient = resty.New()
+
+// GraphQLClient sends GraphQL operations to a single endpoint
+type GraphQLClient struct {
+	Endpoint string
+	Session  *Session
+	client   *resty.Client
+}
+
+// GraphQLEndpoint returns the GraphQL endpoint of the given product,
+// an empty product refers to the platform endpoint
+func GraphQLEndpoint(host *url.URL, product string) string {
+	if product == "" {
+		return fmt.Sprintf("%s/api/graphql/query", host)
+	}
+	return fmt.Sprintf("%s/%s/api/graphql/query", host, product)
+}
+
+// NewGraphQLClient returns a client for the given product which
+// authorizes the requests with the token of the given session
+func NewGraphQLClient(s *Session, product string) *GraphQLClient {
+	return &GraphQLClient{
+		Endpoint: GraphQLEndpoint(s.Credentials.Host, product),
+		Session:  s,
+		client:   httpClient,
+	}
+}
+
+// Do sends the query along with its variables and decodes the data
+// field of the response into result. Errors returned by the server
+// are surfaced as a *GraphQLError. The request is retried once with
+// a refreshed token if the server responds with 401.
+func (g *GraphQLClient) Do(query string, variables map[string]interface{}, result interface{}) error {
+	_, _, err := g.DoConditional(query, variables, "", result)
+	return err
+}
+
+// DoConditional is Do revalidating a previous response with its ETag.
+// notModified is true and result is left as is if the server responds
+// with 304, otherwise the ETag of the new response is returned.
+func (g *GraphQLClient) DoConditional(query string, variables map[string]interface{}, etag string, result interface{}) (newETag string, notModified bool, err error) {
+	resp, err := g.post(query, variables, etag)
+	if err != nil {
+		return "", false, err
+	}
+	if resp.StatusCode() == http.StatusUnauthorized {
+		if err := g.Session.Refresh(); err != nil {
+			return "", false, err
+		}
+		if resp, err = g.post(query, variables, etag); err != nil {
+			return "", false, err
+		}
+	}
+	if etag != "" && resp.StatusCode() == http.StatusNotModified {
+		return etag, true, nil
+	}
+
+	var envelope GraphQLResponse
+	if err := json.Unmarshal(resp.Body(), &envelope); err != nil {
+		if !resp.IsSuccess() {
+			return "", false, &HTTPError{StatusCode: resp.StatusCode(), Status: resp.Status()}
+		}
+		return "", false, fmt.Errorf("invalid graphql response: %w", err)
+	}
+	if len(envelope.Errors) > 0 {
+		return "", false, &GraphQLError{Errors: envelope.Errors}
+	}
+	if !resp.IsSuccess() {
+		return "", false, &HTTPError{StatusCode: resp.StatusCode(), Status: resp.Status()}
+	}
+	if result != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, result); err != nil {
+			return "", false, fmt.Errorf("invalid graphql response: %w", err)
+		}
+	}
+	return resp.Header().Get("ETag"), false, nil
+}
+
+func (g *GraphQLClient) post(query string, variables map[string]interface{}, etag string) (*resty.Response, error) {
+	token, err := g.Session.AccessToken()
+	if err != nil {
+		return nil, err
+	}
+	req := g.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", token)
+	if etag != "" {
+		req.SetHeader("If-None-Match", etag)
+	}
+	return req.
+		SetBody(GraphQLRequest{Query: query, Variables: variables}).
+		Post(g.Endpoint)
+}
@@ -0,0 +1,246 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePortal serves the token and the chaos GraphQL endpoints. The
+// GraphQL requests are answered by the handler, they're rejected
+// with 401 unless they carry the current token.
+type fakePortal struct {
+	mu       sync.Mutex
+	token    string
+	logins   int
+	requests []*http.Request
+	bodies   []GraphQLRequest
+	handler  func(w http.ResponseWriter, r *http.Request)
+}
+
+func (p *fakePortal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch r.URL.Path {
+	case "/api/auth/v1/token":
+		p.logins++
+		p.token = fmt.Sprintf("token-%d", p.logins)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Token{AccessToken: p.token, ExpiresIn: 3600})
+	case "/chaos/api/graphql/query":
+		var body GraphQLRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		p.requests = append(p.requests, r)
+		p.bodies = append(p.bodies, body)
+		if r.Header.Get("Authorization") != p.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p.handler(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestSession starts the portal and returns a session holding
+// the given token, valid for an hour
+func newTestSession(t *testing.T, portal *fakePortal, token string, password string) *Session {
+	t.Helper()
+	server := httptest.NewServer(portal)
+	t.Cleanup(server.Close)
+	host, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Session{
+		Credentials: Credentials{Host: host, Username: "admin", Password: []byte(password)},
+		Token:       Token{AccessToken: token, ExpiresIn: 3600},
+		IssuedAt:    time.Now(),
+	}
+}
+
+func TestGraphQLClientDo(t *testing.T) {
+	type result struct {
+		GetProjects []struct {
+			ID string `json:"id"`
+		} `json:"getProjects"`
+	}
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    string
+		wantErr func(err error) bool
+	}{
+		{
+			name:   "data",
+			status: http.StatusOK,
+			body:   `{"data": {"getProjects": [{"id": "p1"}]}}`,
+			want:   "p1",
+		},
+		{
+			name:   "graphql errors",
+			status: http.StatusOK,
+			body:   `{"data": null, "errors": [{"message": "project not found", "path": ["getProjects"]}, {"message": "denied"}]}`,
+			wantErr: func(err error) bool {
+				var gqlErr *GraphQLError
+				return errors.As(err, &gqlErr) && len(gqlErr.Errors) == 2 &&
+					err.Error() == "graphql: getProjects: project not found; denied"
+			},
+		},
+		{
+			name:   "graphql errors with an error status",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"errors": [{"message": "invalid query"}]}`,
+			wantErr: func(err error) bool {
+				var gqlErr *GraphQLError
+				return errors.As(err, &gqlErr)
+			},
+		},
+		{
+			name:   "error status",
+			status: http.StatusBadGateway,
+			body:   "<html>bad gateway</html>",
+			wantErr: func(err error) bool {
+				var httpErr *HTTPError
+				return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusBadGateway
+			},
+		},
+		{
+			name:   "invalid envelope",
+			status: http.StatusOK,
+			body:   "not json",
+			wantErr: func(err error) bool {
+				return strings.Contains(err.Error(), "invalid graphql response")
+			},
+		},
+		{
+			name:   "data not matching the result",
+			status: http.StatusOK,
+			body:   `{"data": {"getProjects": "p1"}}`,
+			wantErr: func(err error) bool {
+				return strings.Contains(err.Error(), "invalid graphql response")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portal := &fakePortal{token: "token", handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}}
+			s := newTestSession(t, portal, "token", "")
+			var got result
+			err := NewGraphQLClient(s, "chaos").Do("query getProjects { getProjects { id } }", map[string]interface{}{"projectID": "p1"}, &got)
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Errorf("Do() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if len(got.GetProjects) != 1 || got.GetProjects[0].ID != tt.want {
+				t.Errorf("Do() result = %+v, want project %s", got, tt.want)
+			}
+			req, body := portal.requests[0], portal.bodies[0]
+			if req.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q", req.Header.Get("Content-Type"))
+			}
+			if body.Variables["projectID"] != "p1" || !strings.HasPrefix(body.Query, "query getProjects") {
+				t.Errorf("request body = %+v", body)
+			}
+		})
+	}
+}
+
+func TestGraphQLClientRetriesOnceAfter401(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		rejectAll  bool
+		wantErr    error
+		wantStatus int
+		wantLogins int
+		wantPosts  int
+	}{
+		{name: "refreshed token", password: "secret", wantLogins: 1, wantPosts: 2},
+		{name: "no password to refresh with", wantErr: ErrSessionExpired, wantPosts: 1},
+		{name: "rejected again", password: "secret", rejectAll: true, wantStatus: http.StatusUnauthorized, wantLogins: 1, wantPosts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portal := &fakePortal{handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"data": {}}`))
+			}}
+			if tt.rejectAll {
+				portal.handler = func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			}
+			// The server doesn't know the token of the session anymore
+			s := newTestSession(t, portal, "revoked", tt.password)
+			err := NewGraphQLClient(s, "chaos").Do("query { ping }", nil, nil)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantStatus != 0:
+				var httpErr *HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantStatus {
+					t.Errorf("Do() error = %v, want status %d", err, tt.wantStatus)
+				}
+			case err != nil:
+				t.Errorf("Do() error = %v", err)
+			}
+			if portal.logins != tt.wantLogins || len(portal.requests) != tt.wantPosts {
+				t.Errorf("%d logins and %d requests, want %d and %d", portal.logins, len(portal.requests), tt.wantLogins, tt.wantPosts)
+			}
+			if tt.wantLogins > 0 && s.Token.AccessToken != "token-1" {
+				t.Errorf("token of the session = %q, want the refreshed one", s.Token.AccessToken)
+			}
+		})
+	}
+}
+
+func TestGraphQLClientDoConditional(t *testing.T) {
+	portal := &fakePortal{token: "token", handler: func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"data": {"getYAMLData": "kind: ChaosEngine"}}`))
+	}}
+	s := newTestSession(t, portal, "token", "")
+	client := NewGraphQLClient(s, "chaos")
+
+	var result struct {
+		GetYAMLData string `json:"getYAMLData"`
+	}
+	etag, notModified, err := client.DoConditional("query { getYAMLData }", nil, "", &result)
+	if err != nil || notModified || etag != `"v1"` || result.GetYAMLData != "kind: ChaosEngine" {
+		t.Fatalf("DoConditional() = %q, %v, %v, result %+v", etag, notModified, err, result)
+	}
+	if got := portal.requests[0].Header.Get("If-None-Match"); got != "" {
+		t.Errorf("If-None-Match = %q without an ETag", got)
+	}
+
+	result.GetYAMLData = "unchanged"
+	etag, notModified, err = client.DoConditional("query { getYAMLData }", nil, `"v1"`, &result)
+	if err != nil || !notModified || etag != `"v1"` {
+		t.Fatalf("DoConditional() = %q, %v, %v, want not modified", etag, notModified, err)
+	}
+	if result.GetYAMLData != "unchanged" {
+		t.Errorf("result = %q, want it left as is", result.GetYAMLData)
+	}
+}
@@ -1,35 +1,35 @@
 package common
 
 import (
-	"bufio"
 	"fmt"
-	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
 	"net/url"
 	"os"
 	"strings"
 
+	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
+	"github.com/mayadata-io/cli-utils/pkg/common/prompt"
 	"github.com/mayadata-io/cli-utils/pkg/constants"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
+// GetUsername takes the username as input
 func GetUsername() string {
-	var username string
-	fmt.Print("🤔 Username [", constants.DefaultUsername, "]: ")
-	fmt.Scanln(&username)
-	if username == "" {
-		username = constants.DefaultUsername
+	username, err := prompt.Default().Input("🤔 Username", constants.DefaultUsername)
+	if err != nil {
+		return constants.DefaultUsername
 	}
 	return username
 }
 
+// GetPortalURL takes the URL of the portal as input
 func GetPortalURL() (*url.URL, error) {
-	var host string
-	fmt.Print("👉 Kubera Enterprise URL: ")
-	fmt.Scanln(&host)
-	for host == "" {
-		fmt.Println("⛔ Kubera Enterprise URL can't be empty!!")
-		fmt.Print("👉 Kubera Enterprise URL: ")
-		fmt.Scanln(&host)
+	p := prompt.Default()
+	host, err := p.Input("👉 Kubera Enterprise URL", "")
+	for err == nil && host == "" {
+		p.Println("⛔ Kubera Enterprise URL can't be empty!!")
+		host, err = p.Input("👉 Kubera Enterprise URL", "")
+	}
+	if err != nil {
+		return &url.URL{}, err
 	}
 	host = strings.TrimRight(host, "/")
 	newUrl, err := url.Parse(host)
@@ -39,103 +39,106 @@ func GetPortalURL() (*url.URL, error) {
 	return newUrl, nil
 }
 
-func GetPassword() []byte {
-	var pass []byte
-	fmt.Print("🙈 Password: ")
-	pass, _ = terminal.ReadPassword(0)
-	if pass == nil {
-		fmt.Println("\n⛔ Password cannot be empty!")
-		os.Exit(1)
-	}
-	return pass
+// GetPassword takes the password as input without echoing it
+func GetPassword() ([]byte, error) {
+	pass, err := prompt.Default().Password("🙈 Password")
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, ErrEmptyPassword
+	}
+	return pass, nil
 }
 
 // GetMode gets mode of agent installation as input
-func GetMode() string {
-	var mode int
-	fmt.Println("\n🔌 Installation Modes:\n1. Cluster\n2. Namespace")
-	fmt.Print("\n👉 Select Mode [", constants.DefaultMode, "]: ")
-	fmt.Scanln(&mode)
-	if mode == 0 {
-		return "namespace"
-	}
-	for mode < 1 || mode > 2 {
-		fmt.Println("🚫 Invalid mode. Please enter the correct mode")
-		fmt.Print("👉 Select Mode [", constants.DefaultMode, "]: ")
-		fmt.Scanln(&mode)
-	}
-	if mode == 1 {
-		return "cluster"
-	}
-	return constants.DefaultMode
+func GetMode() (string, error) {
+	p := prompt.Default()
+	p.Println("\n🔌 Installation Modes:")
+	modes := []string{"cluster", "namespace"}
+	mode, err := p.Select("\n👉 Select Mode", modes, 1)
+	if err != nil {
+		return "", err
+	}
+	return modes[mode], nil
 }
 
-func Confirm() {
-	var wish string
-	fmt.Print("\n🤷 Do you want to continue with the above details? [Y/N]: ")
-	fmt.Scanln(&wish)
-	if wish == "Y" || wish == "Yes" || wish == "yes" || wish == "y" {
-		fmt.Println("👍 Continuing agent registration!!")
-	} else {
-		fmt.Println("✋ Exiting agent registration!!")
-		os.Exit(1)
+// Confirm asks the user to confirm the agent registration and
+// returns ErrAborted if the user declines
+func Confirm() error {
+	p := prompt.Default()
+	ok, err := p.Confirm("\n🤷 Do you want to continue with the above details?", false)
+	if err != nil {
+		return err
 	}
+	if ok {
+		p.Println("👍 Continuing agent registration!!")
+		return nil
+	}
+	p.Println("✋ Exiting agent registration!!")
+	return ErrAborted
 }
 
-// getPlatformName displays a list of platforms, takes the
-// platform name as input and returns the selected platform
-//
-// TODO --
-// - Entering any character other than numbers returns 0. Input validation need to be done.
-// - If input is given as "123abc", "abc" will be used for next user input. Buffer need to be read completely.
-// - String literals like "AWS" are used at multiple places. Need to be changed to constants.
-func GetPlatformName() string {
-	var platform int
+// GetPlatformName displays the list of registered platforms, takes
+// the platform name as input and returns the selected platform.
+// The discovered platform is selected by default.
+func GetPlatformName() (string, error) {
 	discoveredPlatform := DiscoverPlatform()
-	fmt.Println("📦 Platform List")
-	fmt.Println(constants.PlatformList)
-	fmt.Print("🔎 Select Platform [", discoveredPlatform, "]: ")
-	fmt.Scanln(&platform)
-	switch platform {
-	case 0:
-		return discoveredPlatform
-	case 1:
-		return "AWS"
-	case 2:
-		return "GKE"
-	case 3:
-		return "Openshift"
-	case 4:
-		return "Rancher"
-	default:
-		return constants.DefaultPlatform
+	platforms := Platforms()
+	def := len(platforms) - 1
+	for i, platform := range platforms {
+		if platform == discoveredPlatform {
+			def = i
+		}
 	}
+	p := prompt.Default()
+	p.Println("📦 Platform List")
+	platform, err := p.Select("🔎 Select Platform", platforms, def)
+	if err != nil {
+		return "", err
+	}
+	return platforms[platform], nil
 }
 
+// Scanner reads a line of input
+//
+// Deprecated: use prompt.Default().Input, which prompts as well
 func Scanner() string {
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		return scanner.Text()
-	}
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "reading standard input:", err)
+	if r, ok := prompt.Default().(interface{ ReadLine() (string, error) }); ok {
+		line, err := r.ReadLine()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "reading standard input:", err)
+		}
+		return line
 	}
-	return ""
+	line, _ := prompt.Default().Input("", "")
+	return line
 }
 
 // Summary display the agent details based on input
-func Summary(agent Agent, product string) {
+func Summary(agent Agent, product string) error {
+	nsExists, err := k8s.NsExists(agent.Namespace)
+	if err != nil {
+		return err
+	}
+	saExists := false
+	if product == "chaos" {
+		saExists, err = k8s.SAExists(agent.Namespace, agent.ServiceAccount)
+		if err != nil {
+			return err
+		}
+	}
 	fmt.Println("\n📌 Summary --------------------------")
 	fmt.Println("\nAgent Name:        ", agent.AgentName)
 	fmt.Println("Agent Description: ", agent.Description)
 	fmt.Println("Platform Name:     ", agent.PlatformName)
-	if ok, _ := k8s.NsExists(agent.Namespace); ok {
+	if nsExists {
 		fmt.Println("Namespace:         ", agent.Namespace)
 	} else {
 		fmt.Println("Namespace:         ", agent.Namespace, "(new)")
 	}
 	if product == "chaos" {
-		if k8s.SAExists(agent.Namespace, agent.ServiceAccount) {
+		if saExists {
 			fmt.Println("Service Account:   ", agent.ServiceAccount)
 		} else {
 			fmt.Println("Service Account:   ", agent.ServiceAccount, "(new)")
@@ -143,4 +146,5 @@ func Summary(agent Agent, product string) {
 		fmt.Println("Installation Mode: ", agent.Mode)
 	}
 	fmt.Println("\n-------------------------------------")
+	return nil
 }
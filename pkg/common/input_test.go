@@ -0,0 +1,103 @@
+package common
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
+	"github.com/mayadata-io/cli-utils/pkg/common/prompt"
+	"github.com/mayadata-io/cli-utils/pkg/constants"
+)
+
+// scripted makes the functions of the package prompt with the
+// given answers until the test ends
+func scripted(t *testing.T, answers ...string) *prompt.Scripted {
+	t.Helper()
+	p := prompt.NewScripted(answers...)
+	previous := prompt.Default()
+	prompt.SetDefault(p)
+	t.Cleanup(func() { prompt.SetDefault(previous) })
+	return p
+}
+
+func TestGetMode(t *testing.T) {
+	tests := []struct {
+		answer  string
+		want    string
+		wantErr bool
+	}{
+		{answer: "", want: "namespace"},
+		{answer: "1", want: "cluster"},
+		{answer: "2", want: "namespace"},
+		{answer: "Cluster", want: "cluster"},
+		{answer: "3", wantErr: true},
+		{answer: "123abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			scripted(t, tt.answer)
+			got, err := GetMode()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetMode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []string
+		wantErr error
+	}{
+		{name: "yes", answers: []string{"y"}},
+		{name: "no", answers: []string{"n"}, wantErr: ErrAborted},
+		{name: "empty declines", answers: []string{""}, wantErr: ErrAborted},
+		{name: "no answer", wantErr: prompt.ErrNoAnswer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scripted(t, tt.answers...)
+			if err := Confirm(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Confirm() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetPlatformName(t *testing.T) {
+	// Without a cluster no platform is discovered, so the
+	// default platform is selected by default
+	previous := k8s.Default()
+	k8s.Configure(k8s.ClientOptions{Kubeconfig: filepath.Join(t.Name(), "missing")})
+	t.Cleanup(func() { k8s.Configure(previous.Options) })
+
+	tests := []struct {
+		answer  string
+		want    string
+		wantErr bool
+	}{
+		{answer: "", want: constants.DefaultPlatform},
+		{answer: "1", want: Platforms()[0]},
+		{answer: "gke", want: constants.PlatformGKE},
+		{answer: "Openshift", want: constants.PlatformOpenshift},
+		{answer: "0", wantErr: true},
+		{answer: "123abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			scripted(t, tt.answer)
+			got, err := GetPlatformName()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetPlatformName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetPlatformName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
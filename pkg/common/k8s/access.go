@@ -3,8 +3,7 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
+	"strings"
 
 	authorizationv1 "k8s.io/api/authorization/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -13,6 +12,7 @@ import (
 	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 )
 
+// CanIOptions describe an action to be reviewed, like kubectl auth can-i
 type CanIOptions struct {
 	NoHeaders       bool
 	Namespace       string
@@ -25,16 +25,28 @@ type CanIOptions struct {
 	ResourceName string
 }
 
-func CheckSAPermissions(verb, resource string, print bool) (bool, error) {
-
+// CheckSAPermissions checks if the user can perform the verb on the
+// resource, given as resource.group e.g. roles.rbac.authorization.k8s.io,
+// across the cluster
+func (c *Client) CheckSAPermissions(verb, resource string, print bool) (bool, error) {
 	var o CanIOptions
 	o.Verb = verb
-	o.Resource.Resource = resource
-	client, err := ClientSet()
-	if err != nil {
-		log.Fatal(err)
+	gr := schema.ParseGroupResource(resource)
+	o.Resource.Group = gr.Group
+	o.Resource.Resource = gr.Resource
+	return c.CanI(o, print)
+}
+
+// CanI checks if the user can perform the action described by the options
+func (c *Client) CanI(o CanIOptions, print bool) (bool, error) {
+	AuthClient := o.AuthClient
+	if AuthClient == nil {
+		client, err := c.ClientSet()
+		if err != nil {
+			return false, err
+		}
+		AuthClient = client.AuthorizationV1()
 	}
-	AuthClient := client.AuthorizationV1()
 
 	var sar *authorizationv1.SelfSubjectAccessReview
 	sar = &authorizationv1.SelfSubjectAccessReview{
@@ -57,12 +69,10 @@ func CheckSAPermissions(verb, resource string, print bool) (bool, error) {
 
 	if response.Status.Allowed {
 		if print {
-			fmt.Println("🔑 ", resource, "- ✅")
-		}
-	} else {
-		if print {
-			fmt.Println("🔑 ", resource, "- ❌")
+			fmt.Println("🔑 ", o.Resource.Resource, "- ✅")
 		}
+	} else if print {
+		fmt.Println("🔑 ", o.Resource.Resource, "- ❌")
 		if len(response.Status.Reason) > 0 {
 			fmt.Println(response.Status.Reason)
 		}
@@ -74,36 +84,52 @@ func CheckSAPermissions(verb, resource string, print bool) (bool, error) {
 	return response.Status.Allowed, nil
 }
 
-func ValidateSAPermissions(mode string) {
-	var pems [2]bool
-	var err error
+// ValidateSAPermissions checks if the user has the permissions
+// required to install an agent in the given mode. In namespace mode
+// the roles are checked in the given namespace, across the cluster
+// if it's empty.
+func (c *Client) ValidateSAPermissions(mode, namespace string, print bool) error {
+	resources := []string{"roles", "rolebindings"}
 	if mode == "cluster" {
-		resources := [2]string{"clusterrole", "clusterrolebinding"}
-		i := 0
-		for _, resource := range resources {
-			pems[i], err = CheckSAPermissions("create", resource, true)
-			if err != nil {
-				fmt.Println(err)
-			}
-			i++
-		}
-	} else {
-		resources := [2]string{"role", "rolebinding"}
-		i := 0
-		for _, resource := range resources {
-			pems[i], err = CheckSAPermissions("create", resource, true)
-			if err != nil {
-				fmt.Println(err)
-			}
-			i++
-		}
+		resources = []string{"clusterroles", "clusterrolebindings"}
+		namespace = ""
 	}
 
-	for _, pem := range pems {
+	var missing []string
+	for _, resource := range resources {
+		var o CanIOptions
+		o.Verb = "create"
+		o.Namespace = namespace
+		o.Resource = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Resource: resource}
+		pem, err := c.CanI(o, print)
+		if err != nil {
+			return err
+		}
 		if !pem {
-			fmt.Println("\n🚫 You don't have sufficient permissions.\n🙄 Please use a service account with sufficient permissions.")
-			os.Exit(1)
+			missing = append(missing, resource)
 		}
 	}
-	fmt.Println("\n🌟 Sufficient permissions. Registering Agent")
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: can't create %s", ErrInsufficientPermissions, strings.Join(missing, ", "))
+	}
+	if print {
+		fmt.Println("\n🌟 Sufficient permissions. Registering Agent")
+	}
+	return nil
+}
+
+// CheckSAPermissions calls Client.CheckSAPermissions on the default client
+func CheckSAPermissions(verb, resource string, print bool) (bool, error) {
+	return Default().CheckSAPermissions(verb, resource, print)
+}
+
+// ValidateSAPermissions calls Client.ValidateSAPermissions on the default client
+func ValidateSAPermissions(mode, namespace string) error {
+	return Default().ValidateSAPermissions(mode, namespace, true)
+}
+
+// CanI calls Client.CanI on the default client
+func CanI(o CanIOptions, print bool) (bool, error) {
+	return Default().CanI(o, print)
 }
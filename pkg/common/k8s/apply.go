@@ -0,0 +1,166 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mayadata-io/cli-utils/pkg/constants"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// Actions performed on an object while applying it
+const (
+	ApplyCreated    = "created"
+	ApplyConfigured = "configured"
+	ApplyUnchanged  = "unchanged"
+)
+
+// ApplyResult holds the outcome of applying a single object
+type ApplyResult struct {
+	Kind      string
+	Group     string
+	Name      string
+	Namespace string
+	Action    string
+}
+
+// String formats the result the way kubectl does
+// e.g. deployment.apps/subscriber created
+func (r ApplyResult) String() string {
+	kind := strings.ToLower(r.Kind)
+	if r.Group != "" {
+		kind += "." + r.Group
+	}
+	return fmt.Sprintf("%s/%s %s", kind, r.Name, r.Action)
+}
+
+// DecodeManifest decodes the YAML or JSON documents in the
+// given manifest into objects, empty documents are skipped
+func DecodeManifest(data []byte) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("invalid manifest: object without kind or name")
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// ApplyManifest applies the objects in the given manifest
+// using server-side apply and returns the result per object
+func (c *Client) ApplyManifest(data []byte) ([]ApplyResult, error) {
+	objs, err := DecodeManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := c.ClientSet()
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+
+	var results []ApplyResult
+	for _, obj := range objs {
+		result, err := applyObject(client, mapper, obj)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// applyObject applies a single object, the mapper is reset and the
+// mapping retried once if the kind isn't known, since it may have been
+// defined by a CRD applied earlier in the manifest
+func applyObject(client dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, obj *unstructured.Unstructured) (ApplyResult, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		mapper.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return ApplyResult{}, fmt.Errorf("failed to map %s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+
+	var resource dynamic.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(metav1.NamespaceDefault)
+		}
+		resource = client.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	}
+
+	result := ApplyResult{
+		Kind:      gvk.Kind,
+		Group:     gvk.Group,
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+
+	// The object is fetched before applying it, to find out
+	// if it's being created or updated
+	existing, err := resource.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+	if k8serror.IsNotFound(err) {
+		existing = nil
+	} else if err != nil {
+		return ApplyResult{}, err
+	}
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	force := true
+	applied, err := resource.Patch(context.TODO(), obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: constants.FieldManager,
+		Force:        &force,
+	})
+	if err != nil {
+		return ApplyResult{}, fmt.Errorf("failed to apply %s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+
+	switch {
+	case existing == nil:
+		result.Action = ApplyCreated
+	case existing.GetResourceVersion() == applied.GetResourceVersion():
+		result.Action = ApplyUnchanged
+	default:
+		result.Action = ApplyConfigured
+	}
+	return result, nil
+}
+
+// ApplyManifest calls Client.ApplyManifest on the default client
+func ApplyManifest(data []byte) ([]ApplyResult, error) {
+	return Default().ApplyManifest(data)
+}
@@ -2,36 +2,180 @@ package k8s
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
+	"sync"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 )
 
-// Returns a new kubernetes client set
-func ClientSet() (*kubernetes.Clientset, error) {
-	var kubeconfig *string
-	if home := homedir.HomeDir(); home != "" {
-		kcfg := filepath.Join(home, ".kube", "config")
-		kubeconfig = &kcfg
-	} else {
-		fmt.Println("ERROR: Clientset generation failed!")
-		os.Exit(1)
-	}
-	// create the config
-	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+// ClientOptions decide how the config of the cluster is loaded
+type ClientOptions struct {
+	// Kubeconfig is the path of the kubeconfig file, the standard
+	// loading rules (KUBECONFIG, ~/.kube/config) are followed if empty
+	Kubeconfig string
+	// Context overrides the current context of the kubeconfig
+	Context string
+}
+
+// Client creates the clients of a cluster once and caches them
+type Client struct {
+	Options ClientOptions
+
+	mu        sync.Mutex
+	config    *rest.Config
+	clientset *kubernetes.Clientset
+	dynamic   dynamic.Interface
+
+	valuesMu sync.Mutex
+	values   map[string]interface{}
+}
+
+// NewClient returns a client for the cluster selected by the given options
+func NewClient(opts ClientOptions) *Client {
+	return &Client{Options: opts}
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultClient = NewClient(ClientOptions{})
+)
+
+// Configure sets the options of the client used by the
+// functions of this package
+func Configure(opts ClientOptions) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = NewClient(opts)
+}
+
+// Default returns the client used by the functions of this package
+func Default() *Client {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultClient
+}
+
+// RestConfig returns the config used to connect to the cluster. The
+// kubeconfig is loaded following the standard loading rules and the
+// in-cluster config is used if no kubeconfig is found.
+func (c *Client) RestConfig() (*rest.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.restConfig()
+}
+
+func (c *Client) restConfig() (*rest.Config, error) {
+	if c.config != nil {
+		return c.config, nil
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = c.Options.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: c.Options.Context}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if clientcmd.IsEmptyConfig(err) && c.Options.Kubeconfig == "" && c.Options.Context == "" {
+		config, err = rest.InClusterConfig()
+		if err == rest.ErrNotInCluster {
+			return nil, ErrNoKubeconfig
+		}
+	}
 	if err != nil {
-		fmt.Println("ERROR: ", err.Error())
-		os.Exit(1)
+		return nil, fmt.Errorf("loading kubeconfig failed: %w", err)
+	}
+	c.config = config
+	return config, nil
+}
+
+// ClientSet returns the kubernetes client set of the cluster
+func (c *Client) ClientSet() (*kubernetes.Clientset, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clientset != nil {
+		return c.clientset, nil
+	}
+	config, err := c.restConfig()
+	if err != nil {
+		return nil, err
 	}
-	// create the clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		fmt.Println("ERROR: ", err.Error())
-		os.Exit(1)
+		return nil, fmt.Errorf("clientset generation failed: %w", err)
+	}
+	c.clientset = clientset
+	return clientset, nil
+}
+
+// DynamicClient returns the client for arbitrary resources of the cluster
+func (c *Client) DynamicClient() (dynamic.Interface, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dynamic != nil {
+		return c.dynamic, nil
+	}
+	config, err := c.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("dynamic client generation failed: %w", err)
+	}
+	c.dynamic = client
+	return client, nil
+}
+
+// Cached returns the value stored for the key on the client, the value
+// is fetched and stored by the first call for the key. Values derived
+// from the cluster are cached this way, so that they're dropped along
+// with the client.
+func (c *Client) Cached(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+	if v, ok := c.values[key]; ok {
+		return v, nil
+	}
+	v, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if c.values == nil {
+		c.values = map[string]interface{}{}
+	}
+	c.values[key] = v
+	return v, nil
+}
+
+// RestConfig calls Client.RestConfig on the default client
+func RestConfig() (*rest.Config, error) {
+	return Default().RestConfig()
+}
+
+// ClientSet calls Client.ClientSet on the default client
+func ClientSet() (*kubernetes.Clientset, error) {
+	return Default().ClientSet()
+}
+
+// DynamicClient calls Client.DynamicClient on the default client
+func DynamicClient() (dynamic.Interface, error) {
+	return Default().DynamicClient()
+}
+
+// Contexts returns the names of the contexts in the given kubeconfig,
+// the standard loading rules are followed if the path is empty
+func Contexts(kubeconfig string) ([]string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	config, err := rules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading kubeconfig failed: %w", err)
+	}
+	contexts := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		contexts = append(contexts, name)
 	}
-	return clientset, err
+	sort.Strings(contexts)
+	return contexts, nil
 }
@@ -0,0 +1,38 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClientCached(t *testing.T) {
+	c := NewClient(ClientOptions{})
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	for i := 0; i < 2; i++ {
+		v, err := c.Cached("key", fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != 1 {
+			t.Errorf("Cached() = %v, want the first value", v)
+		}
+	}
+
+	// Failures aren't cached
+	fail := errors.New("unreachable")
+	if _, err := c.Cached("other", func() (interface{}, error) { return nil, fail }); !errors.Is(err, fail) {
+		t.Errorf("Cached() error = %v, want %v", err, fail)
+	}
+	if v, err := c.Cached("other", fetch); err != nil || v != 2 {
+		t.Errorf("Cached() = %v, %v after a failure, want 2", v, err)
+	}
+
+	// Values aren't shared between clients
+	if v, _ := NewClient(ClientOptions{}).Cached("key", fetch); v != 3 {
+		t.Errorf("Cached() = %v on a new client, want 3", v)
+	}
+}
@@ -0,0 +1,28 @@
+package k8s
+
+import "errors"
+
+var (
+	// ErrInsufficientPermissions is returned when the user is not
+	// allowed to perform an action required for agent installation
+	ErrInsufficientPermissions = errors.New("insufficient permissions")
+
+	// ErrSubscriberExists is returned when a subscriber is
+	// already running in the given namespace
+	ErrSubscriberExists = errors.New("subscriber already present")
+
+	// ErrSubscriberNotFound is returned when no subscriber is
+	// running in the given namespace
+	ErrSubscriberNotFound = errors.New("subscriber not found")
+
+	// ErrAgentNotReady is returned when the agent doesn't
+	// become ready within the given time
+	ErrAgentNotReady = errors.New("agent not ready")
+
+	// ErrPreflightFailed is returned when a preflight check
+	// required for agent installation fails
+	ErrPreflightFailed = errors.New("preflight checks failed")
+
+	// ErrNoKubeconfig is returned when the kubeconfig file can't be located
+	ErrNoKubeconfig = errors.New("kubeconfig not found")
+)
@@ -2,10 +2,10 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
 
+	"github.com/mayadata-io/cli-utils/pkg/common/prompt"
 	"github.com/mayadata-io/cli-utils/pkg/constants"
 	v1 "k8s.io/api/core/v1"
 
@@ -14,8 +14,8 @@ import (
 )
 
 // NsExists checks if the given namespace already exists
-func NsExists(namespace string) (bool, error) {
-	clientset, err := ClientSet()
+func (c *Client) NsExists(namespace string) (bool, error) {
+	clientset, err := c.ClientSet()
 	if err != nil {
 		return false, err
 	}
@@ -32,47 +32,83 @@ func NsExists(namespace string) (bool, error) {
 }
 
 // ValidNs takes a valid namespace as input from user
-func ValidNs(label string) (string, bool) {
-	var namespace string
-	var nsExists bool
-	fmt.Print("📁 Enter the namespace (new or existing) [", constants.DefaultNs, "]: ")
-	fmt.Scanln(&namespace)
-	if namespace == "" {
-		namespace = constants.DefaultNs
+func ValidNs(label string) (string, bool, error) {
+	p := prompt.Default()
+	for {
+		namespace, err := p.Input("📁 Enter the namespace (new or existing)", constants.DefaultNs)
+		if err != nil {
+			return "", false, err
+		}
+		nsExists, err := CheckNs(namespace, label)
+		switch {
+		case errors.Is(err, ErrSubscriberExists):
+			p.Println("🚫 Subscriber already present. Please enter a different namespace")
+		case errors.Is(err, ErrInsufficientPermissions):
+			p.Println("🚫 You don't have permissions to create a namespace.\n🙄 Please enter an existing namespace.")
+		case err != nil:
+			return "", false, err
+		default:
+			if nsExists {
+				p.Println("👍 Continuing with", namespace, "namespace")
+			}
+			return namespace, nsExists, nil
+		}
 	}
-	ok, err := NsExists(namespace)
+}
+
+// CheckNs checks if an agent can be installed in the given namespace
+// and returns whether the namespace already exists
+func (c *Client) CheckNs(namespace, label string) (bool, error) {
+	ok, err := c.NsExists(namespace)
 	if err != nil {
-		fmt.Printf("\n Namespace existence check failed: {%s}\n", err.Error())
-		os.Exit(1)
+		return false, fmt.Errorf("namespace existence check failed: %w", err)
 	}
 	if ok {
-		if PodExists(namespace, label) {
-			fmt.Println("🚫 Subscriber already present. Please enter a different namespace")
-			namespace, nsExists = ValidNs(label)
-		} else {
-			nsExists = true
-			fmt.Println("👍 Continuing with", namespace, "namespace")
+		podExists, err := c.PodExists(namespace, label)
+		if err != nil {
+			return true, err
 		}
-	} else {
-		if val, _ := CheckSAPermissions("create", "namespace", false); !val {
-			fmt.Println("🚫 You don't have permissions to create a namespace.\n🙄 Please enter an existing namespace.")
-			namespace, nsExists = ValidNs(label)
+		if podExists {
+			return true, fmt.Errorf("%w in %s namespace", ErrSubscriberExists, namespace)
 		}
-		nsExists = false
+		return true, nil
+	}
+	allowed, err := c.CheckSAPermissions("create", "namespaces", false)
+	if err != nil {
+		return false, err
 	}
-	return namespace, nsExists
+	if !allowed {
+		return false, fmt.Errorf("%w: can't create %s namespace", ErrInsufficientPermissions, namespace)
+	}
+	return false, nil
 }
 
 // CreateNs creates the given namespace
-func CreateNs(namespace string) {
-	clientset, err := ClientSet()
+func (c *Client) CreateNs(namespace string) error {
+	clientset, err := c.ClientSet()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	nsSpec := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s", namespace)}}
-	_, newErr := clientset.CoreV1().Namespaces().Create(context.TODO(), nsSpec, metav1.CreateOptions{})
-	if newErr != nil {
-		log.Fatal(err.Error())
+	nsSpec := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	_, err = clientset.CoreV1().Namespaces().Create(context.TODO(), nsSpec, metav1.CreateOptions{})
+	if err != nil {
+		return err
 	}
 	fmt.Println(namespace, "namespace created successfully")
+	return nil
+}
+
+// NsExists calls Client.NsExists on the default client
+func NsExists(namespace string) (bool, error) {
+	return Default().NsExists(namespace)
+}
+
+// CheckNs calls Client.CheckNs on the default client
+func CheckNs(namespace, label string) (bool, error) {
+	return Default().CheckNs(namespace, label)
+}
+
+// CreateNs calls Client.CreateNs on the default client
+func CreateNs(namespace string) error {
+	return Default().CreateNs(namespace)
 }
@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mayadata-io/cli-utils/pkg/common/prompt"
+	"github.com/mayadata-io/cli-utils/pkg/constants"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeCluster serves the few API calls made while asking
+// for the namespace and the service account
+type fakeCluster struct {
+	// namespaces existing in the cluster, mapped to whether
+	// an agent runs in them
+	namespaces map[string]bool
+	// serviceAccounts existing in the cluster, as namespace/name
+	serviceAccounts map[string]bool
+	// canCreateNs allows the user to create namespaces
+	canCreateNs bool
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews":
+		var review authorizationv1.SelfSubjectAccessReview
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = f.canCreateNs && attrs.Verb == "create" && attrs.Resource == "namespaces"
+		json.NewEncoder(w).Encode(review)
+	case len(parts) == 4 && parts[2] == "namespaces":
+		if _, ok := f.namespaces[parts[3]]; !ok {
+			f.notFound(w, "namespaces", parts[3])
+			return
+		}
+		json.NewEncoder(w).Encode(corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: parts[3]}})
+	case len(parts) == 5 && parts[4] == "pods":
+		var pods corev1.PodList
+		if f.namespaces[parts[3]] {
+			pods.Items = append(pods.Items, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "subscriber", Namespace: parts[3]}})
+		}
+		json.NewEncoder(w).Encode(pods)
+	case len(parts) == 6 && parts[4] == "serviceaccounts":
+		if !f.serviceAccounts[parts[3]+"/"+parts[5]] {
+			f.notFound(w, "serviceaccounts", parts[5])
+			return
+		}
+		json.NewEncoder(w).Encode(corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: parts[5], Namespace: parts[3]}})
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusInternalServerError)
+	}
+}
+
+func (f *fakeCluster) notFound(w http.ResponseWriter, resource, name string) {
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Reason:   metav1.StatusReasonNotFound,
+		Code:     http.StatusNotFound,
+		Message:  fmt.Sprintf("%s %q not found", resource, name),
+	})
+}
+
+// useFakeCluster points the default client to the fake cluster
+// and answers the prompts with the given answers until the test ends
+func useFakeCluster(t *testing.T, cluster *fakeCluster, answers ...string) {
+	t.Helper()
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	kubeconfig := filepath.Join(dir, "config")
+	config := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+current-context: fake
+users:
+- name: fake
+  user:
+    token: fake
+`, server.URL)
+	if err := ioutil.WriteFile(kubeconfig, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	previousClient := Default()
+	Configure(ClientOptions{Kubeconfig: kubeconfig})
+	previousPrompter := prompt.Default()
+	prompt.SetDefault(prompt.NewScripted(answers...))
+	t.Cleanup(func() {
+		Configure(previousClient.Options)
+		prompt.SetDefault(previousPrompter)
+	})
+}
+
+func TestValidNs(t *testing.T) {
+	tests := []struct {
+		name       string
+		cluster    fakeCluster
+		answers    []string
+		want       string
+		wantExists bool
+		wantErr    error
+	}{
+		{
+			name:       "existing namespace",
+			cluster:    fakeCluster{namespaces: map[string]bool{"litmus": false}},
+			answers:    []string{"litmus"},
+			want:       "litmus",
+			wantExists: true,
+		},
+		{
+			name:    "default namespace created",
+			cluster: fakeCluster{canCreateNs: true},
+			answers: []string{""},
+			want:    constants.DefaultNs,
+		},
+		{
+			name:       "namespace having an agent",
+			cluster:    fakeCluster{namespaces: map[string]bool{"busy": true, "litmus": false}},
+			answers:    []string{"busy", "litmus"},
+			want:       "litmus",
+			wantExists: true,
+		},
+		{
+			name:       "new namespace without permissions",
+			cluster:    fakeCluster{namespaces: map[string]bool{"litmus": false}},
+			answers:    []string{"new", "litmus"},
+			want:       "litmus",
+			wantExists: true,
+		},
+		{
+			name:    "no valid answer",
+			cluster: fakeCluster{namespaces: map[string]bool{"busy": true}},
+			answers: []string{"busy"},
+			wantErr: prompt.ErrNoAnswer,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeCluster(t, &tt.cluster, tt.answers...)
+			got, exists, err := ValidNs("app=subscriber")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidNs() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || exists != tt.wantExists {
+				t.Errorf("ValidNs() = %q, %v, want %q, %v", got, exists, tt.want, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestValidSA(t *testing.T) {
+	cluster := fakeCluster{serviceAccounts: map[string]bool{"litmus/litmus-admin": true}}
+	tests := []struct {
+		name       string
+		answers    []string
+		want       string
+		wantExists bool
+		wantErr    error
+	}{
+		{name: "existing service account", answers: []string{"litmus-admin"}, want: "litmus-admin", wantExists: true},
+		{name: "new service account", answers: []string{"chaos"}, want: "chaos"},
+		{name: "default service account", answers: []string{""}, want: constants.DefaultSA},
+		{name: "no answer", wantErr: prompt.ErrNoAnswer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeCluster(t, &cluster, tt.answers...)
+			got, exists, err := ValidSA("litmus")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidSA() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || exists != tt.wantExists {
+				t.Errorf("ValidSA() = %q, %v, want %q, %v", got, exists, tt.want, tt.wantExists)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+)
+
+// permissionWorkers is the number of access reviews sent concurrently
+const permissionWorkers = 10
+
+// applyVerbs are the verbs needed to apply an object with server-side apply
+var applyVerbs = []string{"get", "create", "patch"}
+
+// PermissionRule is a single action on a resource, an empty
+// namespace refers to the whole cluster
+type PermissionRule struct {
+	Group     string `json:"group"`
+	Resource  string `json:"resource"`
+	Verb      string `json:"verb"`
+	Namespace string `json:"namespace"`
+}
+
+// String formats the rule like kubectl auth can-i
+// e.g. create deployments.apps -n litmus
+func (r PermissionRule) String() string {
+	resource := r.Resource
+	if r.Group != "" {
+		resource += "." + r.Group
+	}
+	if r.Namespace == "" {
+		return r.Verb + " " + resource
+	}
+	return fmt.Sprintf("%s %s -n %s", r.Verb, resource, r.Namespace)
+}
+
+// PermissionResult holds the outcome of reviewing a rule
+type PermissionResult struct {
+	PermissionRule
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+// PermissionMatrix holds the results of reviewing a set of rules
+type PermissionMatrix []PermissionResult
+
+// Missing returns the rules which aren't allowed
+func (m PermissionMatrix) Missing() PermissionMatrix {
+	var missing PermissionMatrix
+	for _, result := range m {
+		if !result.Allowed {
+			missing = append(missing, result)
+		}
+	}
+	return missing
+}
+
+// Err returns an error wrapping ErrInsufficientPermissions listing
+// the missing rules, nil is returned if all of them are allowed
+func (m PermissionMatrix) Err() error {
+	missing := m.Missing()
+	if len(missing) == 0 {
+		return nil
+	}
+	rules := make([]string, len(missing))
+	for i, result := range missing {
+		rules[i] = result.String()
+	}
+	return fmt.Errorf("%w: can't %s", ErrInsufficientPermissions, strings.Join(rules, ", "))
+}
+
+// Print writes the matrix as a table to w, os.Stdout is used if nil
+func (m PermissionMatrix) Print(w io.Writer) error {
+	if w == nil {
+		w = os.Stdout
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tRESOURCE\tVERB\tNAMESPACE\tALLOWED")
+	for _, result := range m {
+		group := result.Group
+		if group == "" {
+			group = "core"
+		}
+		namespace := result.Namespace
+		if namespace == "" {
+			namespace = "*"
+		}
+		allowed := "✅"
+		if !result.Allowed {
+			allowed = "❌"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", group, result.Resource, result.Verb, namespace, allowed)
+	}
+	return tw.Flush()
+}
+
+// CheckPermissions reviews the given rules concurrently with
+// SelfSubjectAccessReviews and returns the result per rule,
+// in the order of the rules
+func (c *Client) CheckPermissions(ctx context.Context, rules []PermissionRule) (PermissionMatrix, error) {
+	clientset, err := c.ClientSet()
+	if err != nil {
+		return nil, err
+	}
+	reviews := clientset.AuthorizationV1().SelfSubjectAccessReviews()
+
+	matrix := make(PermissionMatrix, len(rules))
+	errs := make([]error, len(rules))
+	sem := make(chan struct{}, permissionWorkers)
+	var wg sync.WaitGroup
+	for i, rule := range rules {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, rule PermissionRule) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			sar := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace: rule.Namespace,
+						Verb:      rule.Verb,
+						Group:     rule.Group,
+						Resource:  rule.Resource,
+					},
+				},
+			}
+			response, err := reviews.Create(ctx, sar, metav1.CreateOptions{})
+			if err != nil {
+				errs[i] = fmt.Errorf("access review of %s failed: %w", rule, err)
+				return
+			}
+			reason := response.Status.Reason
+			if response.Status.EvaluationError != "" {
+				reason = strings.TrimSpace(reason + " " + response.Status.EvaluationError)
+			}
+			matrix[i] = PermissionResult{PermissionRule: rule, Allowed: response.Status.Allowed, Reason: reason}
+		}(i, rule)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matrix, nil
+}
+
+// ManifestPermissions returns the rules needed to apply the given
+// manifest. Objects without a namespace are placed in namespace,
+// the default namespace is used if it's empty. The rules granted by
+// the roles in the manifest are included, since a role can only be
+// created by a user holding its permissions.
+func (c *Client) ManifestPermissions(data []byte, namespace string) ([]PermissionRule, error) {
+	objs, err := DecodeManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	clientset, err := c.ClientSet()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+	crds := manifestCRDs(objs)
+
+	seen := map[PermissionRule]bool{}
+	var rules []PermissionRule
+	add := func(rule PermissionRule) {
+		if !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, rule)
+		}
+	}
+
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		resource, namespaced, err := resourceFor(mapper, crds, gvk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+		objNamespace := ""
+		if namespaced {
+			objNamespace = obj.GetNamespace()
+			if objNamespace == "" {
+				objNamespace = namespace
+			}
+		}
+		for _, verb := range applyVerbs {
+			add(PermissionRule{Group: gvk.Group, Resource: resource, Verb: verb, Namespace: objNamespace})
+		}
+
+		if gvk.Group != "rbac.authorization.k8s.io" || (gvk.Kind != "Role" && gvk.Kind != "ClusterRole") {
+			continue
+		}
+		policies, _, err := unstructured.NestedSlice(obj.Object, "rules")
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules in %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+		for _, policy := range policies {
+			p, ok := policy.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			groups, _, _ := unstructured.NestedStringSlice(p, "apiGroups")
+			resources, _, _ := unstructured.NestedStringSlice(p, "resources")
+			verbs, _, _ := unstructured.NestedStringSlice(p, "verbs")
+			for _, group := range groups {
+				for _, resource := range resources {
+					for _, verb := range verbs {
+						add(PermissionRule{Group: group, Resource: resource, Verb: verb, Namespace: objNamespace})
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Resource < b.Resource
+	})
+	return rules, nil
+}
+
+// ManifestPermissionMatrix reviews the rules needed to apply the given manifest
+func (c *Client) ManifestPermissionMatrix(ctx context.Context, data []byte, namespace string) (PermissionMatrix, error) {
+	rules, err := c.ManifestPermissions(data, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return c.CheckPermissions(ctx, rules)
+}
+
+// crdResource is a resource defined by a CRD in the manifest
+type crdResource struct {
+	plural     string
+	namespaced bool
+}
+
+// manifestCRDs returns the resources defined by the CRDs in the
+// given objects, they aren't known to the cluster before applying
+func manifestCRDs(objs []*unstructured.Unstructured) map[schema.GroupKind]crdResource {
+	crds := map[schema.GroupKind]crdResource{}
+	for _, obj := range objs {
+		if obj.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+		plural, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "plural")
+		scope, _, _ := unstructured.NestedString(obj.Object, "spec", "scope")
+		crds[schema.GroupKind{Group: group, Kind: kind}] = crdResource{
+			plural:     plural,
+			namespaced: scope != "Cluster",
+		}
+	}
+	return crds
+}
+
+// resourceFor returns the resource of the given kind and if it's namespaced
+func resourceFor(mapper meta.RESTMapper, crds map[schema.GroupKind]crdResource, gvk schema.GroupVersionKind) (string, bool, error) {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err == nil {
+		return mapping.Resource.Resource, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+	}
+	if crd, ok := crds[gvk.GroupKind()]; ok && meta.IsNoMatchError(err) {
+		return crd.plural, crd.namespaced, nil
+	}
+	return "", false, err
+}
+
+// CheckPermissions calls Client.CheckPermissions on the default client
+func CheckPermissions(ctx context.Context, rules []PermissionRule) (PermissionMatrix, error) {
+	return Default().CheckPermissions(ctx, rules)
+}
+
+// ManifestPermissions calls Client.ManifestPermissions on the default client
+func ManifestPermissions(data []byte, namespace string) ([]PermissionRule, error) {
+	return Default().ManifestPermissions(data, namespace)
+}
+
+// ManifestPermissionMatrix calls Client.ManifestPermissionMatrix on the default client
+func ManifestPermissionMatrix(ctx context.Context, data []byte, namespace string) (PermissionMatrix, error) {
+	return Default().ManifestPermissionMatrix(ctx, data, namespace)
+}
@@ -2,37 +2,16 @@ package k8s
 
 import (
 	"context"
-	"fmt"
-	"log"
 
-	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// WatchPod watches for the pod status
-func WatchPod(namespace, label string) {
-	clientset, err := ClientSet()
-	if err != nil {
-		log.Fatal(err)
-	}
-	watch, err := clientset.CoreV1().Pods(namespace).Watch(context.TODO(), metav1.ListOptions{
-		LabelSelector: label,
-	})
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-	for event := range watch.ResultChan() {
-		p, ok := event.Object.(*v1.Pod)
-		if !ok {
-			log.Fatal("unexpected type")
-		}
-		fmt.Println("💡 Connecting agent to Kubera Enterprise.")
-		if p.Status.Phase == "Running" {
-			fmt.Println("🏃 Agents running!!")
-			watch.Stop()
-			break
-		}
-	}
+// WatchPod waits for the pods with the given label to be running
+//
+// Deprecated: use WaitForAgentReady, which is bounded by a timeout
+// and reports the containers that fail to start
+func (c *Client) WatchPod(namespace, label string) error {
+	return c.WaitForAgentReady(context.Background(), namespace, label, DefaultReadyTimeout, nil)
 }
 
 type PodList struct {
@@ -40,19 +19,28 @@ type PodList struct {
 }
 
 // PodExists checks if the pod with the given label already exists in the given namespace
-func PodExists(namespace, label string) bool {
-	clientset, err := ClientSet()
+func (c *Client) PodExists(namespace, label string) (bool, error) {
+	clientset, err := c.ClientSet()
 	if err != nil {
-		log.Fatal(err)
+		return false, err
 	}
-	watch, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
+	pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
 		LabelSelector: label,
 	})
 	if err != nil {
-		log.Fatal(err.Error())
-	}
-	if len(watch.Items) >= 1 {
-		return true
+		return false, err
 	}
-	return false
+	return len(pods.Items) >= 1, nil
+}
+
+// WatchPod calls Client.WatchPod on the default client
+//
+// Deprecated: use WaitForAgentReady
+func WatchPod(namespace, label string) error {
+	return Default().WatchPod(namespace, label)
+}
+
+// PodExists calls Client.PodExists on the default client
+func PodExists(namespace, label string) (bool, error) {
+	return Default().PodExists(namespace, label)
 }
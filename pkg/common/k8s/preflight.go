@@ -0,0 +1,374 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// Status of a preflight check
+const (
+	PreflightPass = "pass"
+	PreflightWarn = "warn"
+	PreflightFail = "fail"
+)
+
+// Defaults used by the preflight checks
+const (
+	DefaultMinServerVersion = "v1.15.0"
+	DefaultMinNodes         = 1
+	DefaultMinCPU           = "1"
+	DefaultMinMemory        = "2Gi"
+)
+
+// CRD groups installed along with the agent
+var agentCRDGroups = []string{"litmuschaos.io", "argoproj.io"}
+
+// CheckResult is the outcome of a preflight check
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Check is a single preflight check run against a cluster
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, c *Client) (status string, message string)
+}
+
+// PreflightReport holds the results of the preflight checks
+type PreflightReport struct {
+	Results []CheckResult
+}
+
+// Failed checks if any of the checks failed
+func (r PreflightReport) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == PreflightFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns an error wrapping ErrPreflightFailed listing
+// the failed checks, nil is returned if none failed
+func (r PreflightReport) Err() error {
+	var failed []string
+	for _, result := range r.Results {
+		if result.Status == PreflightFail {
+			failed = append(failed, fmt.Sprintf("%s: %s", result.Name, result.Message))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrPreflightFailed, strings.Join(failed, "; "))
+}
+
+// Print writes the report to w, os.Stdout is used if nil
+func (r PreflightReport) Print(w io.Writer) {
+	if w == nil {
+		w = os.Stdout
+	}
+	icons := map[string]string{
+		PreflightPass: "✅",
+		PreflightWarn: "⚠️ ",
+		PreflightFail: "❌",
+	}
+	for _, result := range r.Results {
+		fmt.Fprintf(w, "%s %s: %s\n", icons[result.Status], result.Name, result.Message)
+	}
+}
+
+// PreflightOptions decide which checks are run and their thresholds
+type PreflightOptions struct {
+	// Mode of installation, either cluster or namespace
+	Mode string
+	// Namespace the agent is installed in
+	Namespace string
+	// PortalURL is checked for reachability if set
+	PortalURL string
+	// SkipPermissions leaves the permissions check out, e.g. when
+	// the registration yaml is applied by someone else
+	SkipPermissions bool
+	// MinServerVersion is the lowest supported kubernetes version
+	MinServerVersion string
+	// MinNodes is the number of nodes required
+	MinNodes int
+	// MinCPU and MinMemory are the total allocatable resources
+	// of the nodes required, in kubernetes quantities
+	MinCPU    string
+	MinMemory string
+}
+
+// AgentPermissions returns the rules needed to install the agent
+// in the given mode, namespaced resources are checked in namespace.
+// The CRDs are only installed in cluster mode, in namespace mode they
+// are installed by a cluster admin beforehand and CRDCheck checks them.
+func AgentPermissions(mode, namespace string) []PermissionRule {
+	type permission struct {
+		group    string
+		resource string
+		verbs    []string
+	}
+	perms := []permission{
+		{"", "configmaps", []string{"get", "create", "update"}},
+		{"", "secrets", []string{"get", "create", "update"}},
+		{"", "serviceaccounts", []string{"get", "create"}},
+		{"apps", "deployments", []string{"get", "list", "create", "update"}},
+	}
+	var clusterPerms []permission
+	if mode == "cluster" {
+		clusterPerms = append(clusterPerms,
+			permission{"apiextensions.k8s.io", "customresourcedefinitions", []string{"get", "create", "update"}},
+			permission{"rbac.authorization.k8s.io", "clusterroles", []string{"get", "create"}},
+			permission{"rbac.authorization.k8s.io", "clusterrolebindings", []string{"get", "create"}},
+		)
+	} else {
+		perms = append(perms,
+			permission{"rbac.authorization.k8s.io", "roles", []string{"get", "create"}},
+			permission{"rbac.authorization.k8s.io", "rolebindings", []string{"get", "create"}},
+		)
+	}
+
+	var rules []PermissionRule
+	for _, p := range perms {
+		for _, verb := range p.verbs {
+			rules = append(rules, PermissionRule{Group: p.group, Resource: p.resource, Verb: verb, Namespace: namespace})
+		}
+	}
+	for _, p := range clusterPerms {
+		for _, verb := range p.verbs {
+			rules = append(rules, PermissionRule{Group: p.group, Resource: p.resource, Verb: verb})
+		}
+	}
+	return rules
+}
+
+// DefaultChecks returns the checks run before installing an agent
+func DefaultChecks(opts PreflightOptions) []Check {
+	var checks []Check
+	if !opts.SkipPermissions {
+		checks = append(checks, RBACCheck(opts.Mode, opts.Namespace))
+	}
+	checks = append(checks,
+		ServerVersionCheck(opts.MinServerVersion),
+		CRDCheck(opts.Mode),
+		NodeCheck(opts.MinNodes, opts.MinCPU, opts.MinMemory),
+	)
+	if opts.PortalURL != "" {
+		checks = append(checks, PortalCheck(opts.PortalURL))
+	}
+	return checks
+}
+
+// RunPreflight runs the given checks concurrently and returns
+// their results in the order of the checks
+func (c *Client) RunPreflight(ctx context.Context, checks []Check) PreflightReport {
+	report := PreflightReport{Results: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			status, message := check.Run(ctx, c)
+			report.Results[i] = CheckResult{Name: check.Name, Status: status, Message: message}
+		}(i, check)
+	}
+	wg.Wait()
+	return report
+}
+
+// Preflight runs the default checks with the given options
+func (c *Client) Preflight(ctx context.Context, opts PreflightOptions) PreflightReport {
+	return c.RunPreflight(ctx, DefaultChecks(opts))
+}
+
+// RBACCheck checks if the user is allowed to create the resources of
+// the agent. Namespaced resources are checked in the given namespace.
+func RBACCheck(mode, namespace string) Check {
+	return Check{
+		Name: "permissions",
+		Run: func(ctx context.Context, c *Client) (string, string) {
+			matrix, err := c.CheckPermissions(ctx, AgentPermissions(mode, namespace))
+			if err != nil {
+				return PreflightFail, err.Error()
+			}
+			if err := matrix.Err(); err != nil {
+				return PreflightFail, err.Error()
+			}
+			return PreflightPass, fmt.Sprintf("sufficient permissions for %s mode", mode)
+		},
+	}
+}
+
+// ServerVersionCheck checks if the kubernetes version of the
+// cluster is at least the given version
+func ServerVersionCheck(min string) Check {
+	if min == "" {
+		min = DefaultMinServerVersion
+	}
+	return Check{
+		Name: "server version",
+		Run: func(ctx context.Context, c *Client) (string, string) {
+			clientset, err := c.ClientSet()
+			if err != nil {
+				return PreflightFail, err.Error()
+			}
+			info, err := clientset.Discovery().ServerVersion()
+			if err != nil {
+				return PreflightFail, fmt.Sprintf("fetching server version failed: %v", err)
+			}
+			minVersion, err := version.ParseGeneric(min)
+			if err != nil {
+				return PreflightWarn, fmt.Sprintf("invalid minimum version %s: %v", min, err)
+			}
+			serverVersion, err := version.ParseGeneric(info.GitVersion)
+			if err != nil {
+				return PreflightWarn, fmt.Sprintf("unknown server version %s", info.GitVersion)
+			}
+			if serverVersion.LessThan(minVersion) {
+				return PreflightFail, fmt.Sprintf("%s is older than the minimum supported %s", info.GitVersion, min)
+			}
+			return PreflightPass, info.GitVersion
+		},
+	}
+}
+
+// CRDCheck checks the Litmus and Argo CRDs. In cluster mode it warns
+// if they're already installed, the agent then uses the existing
+// versions of these CRDs. In namespace mode the agent can't install
+// them, so it fails if they're missing.
+func CRDCheck(mode string) Check {
+	return Check{
+		Name: "CRDs",
+		Run: func(ctx context.Context, c *Client) (string, string) {
+			clientset, err := c.ClientSet()
+			if err != nil {
+				return PreflightFail, err.Error()
+			}
+			groups, err := clientset.Discovery().ServerGroups()
+			if err != nil {
+				return PreflightWarn, fmt.Sprintf("fetching API groups failed: %v", err)
+			}
+			var found, missing []string
+			for _, name := range agentCRDGroups {
+				served := false
+				for _, g := range groups.Groups {
+					if g.Name == name {
+						served = true
+						found = append(found, fmt.Sprintf("%s (%s)", g.Name, g.PreferredVersion.Version))
+					}
+				}
+				if !served {
+					missing = append(missing, name)
+				}
+			}
+			if mode != "cluster" {
+				if len(missing) > 0 {
+					return PreflightFail, fmt.Sprintf("%s not installed, a cluster admin has to install them in namespace mode", strings.Join(missing, ", "))
+				}
+				return PreflightPass, "installed: " + strings.Join(found, ", ")
+			}
+			if len(found) > 0 {
+				return PreflightWarn, "already installed: " + strings.Join(found, ", ")
+			}
+			return PreflightPass, "no conflicting CRDs"
+		},
+	}
+}
+
+// NodeCheck checks the number of nodes and their total allocatable resources
+func NodeCheck(minNodes int, minCPU, minMemory string) Check {
+	if minNodes <= 0 {
+		minNodes = DefaultMinNodes
+	}
+	if minCPU == "" {
+		minCPU = DefaultMinCPU
+	}
+	if minMemory == "" {
+		minMemory = DefaultMinMemory
+	}
+	return Check{
+		Name: "nodes",
+		Run: func(ctx context.Context, c *Client) (string, string) {
+			wantCPU, err := resource.ParseQuantity(minCPU)
+			if err != nil {
+				return PreflightWarn, fmt.Sprintf("invalid minimum cpu %s: %v", minCPU, err)
+			}
+			wantMemory, err := resource.ParseQuantity(minMemory)
+			if err != nil {
+				return PreflightWarn, fmt.Sprintf("invalid minimum memory %s: %v", minMemory, err)
+			}
+			clientset, err := c.ClientSet()
+			if err != nil {
+				return PreflightFail, err.Error()
+			}
+			nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				// Users of namespace mode may not be allowed to list nodes
+				return PreflightWarn, fmt.Sprintf("listing nodes failed: %v", err)
+			}
+
+			cpu := resource.Quantity{}
+			memory := resource.Quantity{}
+			for _, node := range nodes.Items {
+				cpu.Add(*node.Status.Allocatable.Cpu())
+				memory.Add(*node.Status.Allocatable.Memory())
+			}
+			summary := fmt.Sprintf("%d nodes, %s cpu and %s memory allocatable", len(nodes.Items), cpu.String(), memory.String())
+			if len(nodes.Items) < minNodes {
+				return PreflightFail, fmt.Sprintf("%s, %d required", summary, minNodes)
+			}
+			if cpu.Cmp(wantCPU) < 0 || memory.Cmp(wantMemory) < 0 {
+				return PreflightWarn, fmt.Sprintf("%s, %s cpu and %s memory recommended", summary, minCPU, minMemory)
+			}
+			return PreflightPass, summary
+		},
+	}
+}
+
+// PortalCheck checks if the portal can be reached from this machine
+func PortalCheck(portalURL string) Check {
+	return Check{
+		Name: "portal",
+		Run: func(ctx context.Context, c *Client) (string, string) {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			req, err := http.NewRequest(http.MethodGet, portalURL, nil)
+			if err != nil {
+				return PreflightFail, fmt.Sprintf("invalid portal URL: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+			if err != nil {
+				return PreflightFail, fmt.Sprintf("%s is not reachable: %v", portalURL, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return PreflightWarn, fmt.Sprintf("%s responded with %s", portalURL, resp.Status)
+			}
+			return PreflightPass, portalURL + " is reachable"
+		},
+	}
+}
+
+// RunPreflight calls Client.RunPreflight on the default client
+func RunPreflight(ctx context.Context, checks []Check) PreflightReport {
+	return Default().RunPreflight(ctx, checks)
+}
+
+// Preflight calls Client.Preflight on the default client
+func Preflight(ctx context.Context, opts PreflightOptions) PreflightReport {
+	return Default().Preflight(ctx, opts)
+}
@@ -0,0 +1,55 @@
+package k8s
+
+import "testing"
+
+func TestAgentPermissions(t *testing.T) {
+	tests := []struct {
+		mode          string
+		wantCRDs      bool
+		wantResources []string
+	}{
+		{mode: "cluster", wantCRDs: true, wantResources: []string{"clusterroles", "clusterrolebindings"}},
+		{mode: "namespace", wantResources: []string{"roles", "rolebindings"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			rules := AgentPermissions(tt.mode, "litmus")
+			resources := map[string]bool{}
+			for _, rule := range rules {
+				resources[rule.Resource] = true
+				clusterScoped := rule.Resource == "customresourcedefinitions" ||
+					rule.Resource == "clusterroles" || rule.Resource == "clusterrolebindings"
+				if clusterScoped && rule.Namespace != "" {
+					t.Errorf("%s checked in %s namespace, want across the cluster", rule.Resource, rule.Namespace)
+				}
+				if !clusterScoped && rule.Namespace != "litmus" {
+					t.Errorf("%s checked in %q namespace, want litmus", rule.Resource, rule.Namespace)
+				}
+			}
+			if resources["customresourcedefinitions"] != tt.wantCRDs {
+				t.Errorf("customresourcedefinitions required = %v, want %v", resources["customresourcedefinitions"], tt.wantCRDs)
+			}
+			for _, resource := range tt.wantResources {
+				if !resources[resource] {
+					t.Errorf("%s not required", resource)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultChecks(t *testing.T) {
+	names := func(checks []Check) map[string]bool {
+		m := map[string]bool{}
+		for _, check := range checks {
+			m[check.Name] = true
+		}
+		return m
+	}
+	if !names(DefaultChecks(PreflightOptions{Mode: "namespace"}))["permissions"] {
+		t.Error("permissions aren't checked by default")
+	}
+	if names(DefaultChecks(PreflightOptions{Mode: "namespace", SkipPermissions: true}))["permissions"] {
+		t.Error("permissions checked with SkipPermissions")
+	}
+}
@@ -0,0 +1,286 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mayadata-io/cli-utils/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ProvisionOptions describe the objects created for an agent
+type ProvisionOptions struct {
+	// Mode of installation, either cluster or namespace
+	Mode           string
+	Namespace      string
+	ServiceAccount string
+	// Owner is the name of the agent the objects are created for,
+	// it's recorded in the ownership labels
+	Owner string
+}
+
+// agentRules are the permissions granted to the service account of
+// the agent, they're limited to the namespace in namespace mode
+var agentRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"pods", "pods/log", "events", "services", "configmaps", "secrets"},
+		Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+	},
+	{
+		APIGroups: []string{"apps"},
+		Resources: []string{"deployments", "statefulsets", "daemonsets", "replicasets"},
+		Verbs:     []string{"get", "list", "watch", "update", "patch"},
+	},
+	{
+		APIGroups: []string{"batch"},
+		Resources: []string{"jobs"},
+		Verbs:     []string{"get", "list", "watch", "create", "delete"},
+	},
+	{
+		APIGroups: []string{"litmuschaos.io"},
+		Resources: []string{"chaosengines", "chaosexperiments", "chaosresults"},
+		Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+	},
+	{
+		APIGroups: []string{"argoproj.io"},
+		Resources: []string{"workflows", "workflows/finalizers", "workflowtemplates", "cronworkflows"},
+		Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+	},
+}
+
+// clusterRules are the permissions granted in addition to
+// agentRules in cluster mode
+var clusterRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"nodes", "namespaces"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// OwnerLabels returns the labels set on the objects created for
+// the given agent, they're used to find the objects on teardown
+func OwnerLabels(owner string) map[string]string {
+	value := invalidLabelChars.ReplaceAllString(owner, "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return map[string]string{
+		constants.ManagedByLabel: constants.FieldManager,
+		constants.OwnerLabel:     strings.Trim(value, "-_."),
+	}
+}
+
+// rbacName returns the name of the role and the role binding of the agent
+func rbacName(opts ProvisionOptions) string {
+	return opts.ServiceAccount + "-" + constants.FieldManager
+}
+
+// Provision creates the namespace, the service account and the role
+// and role binding of the agent, or the cluster role and cluster role
+// binding in cluster mode. Objects which already exist are left as they
+// are, the objects created are labelled with OwnerLabels and returned.
+func (c *Client) Provision(ctx context.Context, opts ProvisionOptions) ([]string, error) {
+	clientset, err := c.ClientSet()
+	if err != nil {
+		return nil, err
+	}
+	meta := func(namespace, name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: OwnerLabels(opts.Owner)}
+	}
+	var created []string
+	record := func(object string, err error) error {
+		if k8serror.IsAlreadyExists(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", object, err)
+		}
+		created = append(created, object)
+		return nil
+	}
+
+	// Existence is checked first, since users of namespace mode
+	// may not be allowed to create namespaces at all
+	nsExists, err := c.NsExists(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !nsExists {
+		_, err = clientset.CoreV1().Namespaces().Create(ctx, &v1.Namespace{
+			ObjectMeta: meta("", opts.Namespace),
+		}, metav1.CreateOptions{})
+		if err := record("namespace/"+opts.Namespace, err); err != nil {
+			return created, err
+		}
+	}
+
+	saExists, err := c.SAExists(opts.Namespace, opts.ServiceAccount)
+	if err != nil {
+		return created, err
+	}
+	if !saExists {
+		_, err = clientset.CoreV1().ServiceAccounts(opts.Namespace).Create(ctx, &v1.ServiceAccount{
+			ObjectMeta: meta(opts.Namespace, opts.ServiceAccount),
+		}, metav1.CreateOptions{})
+		if err := record("serviceaccount/"+opts.ServiceAccount, err); err != nil {
+			return created, err
+		}
+	}
+
+	name := rbacName(opts)
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      opts.ServiceAccount,
+		Namespace: opts.Namespace,
+	}}
+	if opts.Mode == "cluster" {
+		rules := append(append([]rbacv1.PolicyRule{}, agentRules...), clusterRules...)
+		_, err = clientset.RbacV1().ClusterRoles().Create(ctx, &rbacv1.ClusterRole{
+			ObjectMeta: meta("", name),
+			Rules:      rules,
+		}, metav1.CreateOptions{})
+		if err := record("clusterrole.rbac.authorization.k8s.io/"+name, err); err != nil {
+			return created, err
+		}
+		_, err = clientset.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
+			ObjectMeta: meta("", name),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+			Subjects:   subjects,
+		}, metav1.CreateOptions{})
+		if err := record("clusterrolebinding.rbac.authorization.k8s.io/"+name, err); err != nil {
+			return created, err
+		}
+		return created, nil
+	}
+
+	_, err = clientset.RbacV1().Roles(opts.Namespace).Create(ctx, &rbacv1.Role{
+		ObjectMeta: meta(opts.Namespace, name),
+		Rules:      agentRules,
+	}, metav1.CreateOptions{})
+	if err := record("role.rbac.authorization.k8s.io/"+name, err); err != nil {
+		return created, err
+	}
+	_, err = clientset.RbacV1().RoleBindings(opts.Namespace).Create(ctx, &rbacv1.RoleBinding{
+		ObjectMeta: meta(opts.Namespace, name),
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+		Subjects:   subjects,
+	}, metav1.CreateOptions{})
+	if err := record("rolebinding.rbac.authorization.k8s.io/"+name, err); err != nil {
+		return created, err
+	}
+	return created, nil
+}
+
+// Teardown deletes the objects created by Provision for the given
+// agent and mode, objects without its ownership labels are never
+// deleted. The objects deleted are returned.
+func (c *Client) Teardown(ctx context.Context, opts ProvisionOptions) ([]string, error) {
+	clientset, err := c.ClientSet()
+	if err != nil {
+		return nil, err
+	}
+	list := metav1.ListOptions{LabelSelector: labels.SelectorFromSet(OwnerLabels(opts.Owner)).String()}
+	var deleted []string
+	record := func(object string, err error) error {
+		if k8serror.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", object, err)
+		}
+		deleted = append(deleted, object)
+		return nil
+	}
+
+	// Bindings are deleted before the roles they refer to
+	if opts.Mode == "cluster" {
+		crbs, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, list)
+		if err != nil {
+			return deleted, err
+		}
+		for _, crb := range crbs.Items {
+			err := clientset.RbacV1().ClusterRoleBindings().Delete(ctx, crb.Name, metav1.DeleteOptions{})
+			if err := record("clusterrolebinding.rbac.authorization.k8s.io/"+crb.Name, err); err != nil {
+				return deleted, err
+			}
+		}
+		crs, err := clientset.RbacV1().ClusterRoles().List(ctx, list)
+		if err != nil {
+			return deleted, err
+		}
+		for _, cr := range crs.Items {
+			err := clientset.RbacV1().ClusterRoles().Delete(ctx, cr.Name, metav1.DeleteOptions{})
+			if err := record("clusterrole.rbac.authorization.k8s.io/"+cr.Name, err); err != nil {
+				return deleted, err
+			}
+		}
+	} else {
+		rbs, err := clientset.RbacV1().RoleBindings(opts.Namespace).List(ctx, list)
+		if err != nil {
+			return deleted, err
+		}
+		for _, rb := range rbs.Items {
+			err := clientset.RbacV1().RoleBindings(opts.Namespace).Delete(ctx, rb.Name, metav1.DeleteOptions{})
+			if err := record("rolebinding.rbac.authorization.k8s.io/"+rb.Name, err); err != nil {
+				return deleted, err
+			}
+		}
+		roles, err := clientset.RbacV1().Roles(opts.Namespace).List(ctx, list)
+		if err != nil {
+			return deleted, err
+		}
+		for _, role := range roles.Items {
+			err := clientset.RbacV1().Roles(opts.Namespace).Delete(ctx, role.Name, metav1.DeleteOptions{})
+			if err := record("role.rbac.authorization.k8s.io/"+role.Name, err); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	sas, err := clientset.CoreV1().ServiceAccounts(opts.Namespace).List(ctx, list)
+	if err != nil {
+		return deleted, err
+	}
+	for _, sa := range sas.Items {
+		err := clientset.CoreV1().ServiceAccounts(opts.Namespace).Delete(ctx, sa.Name, metav1.DeleteOptions{})
+		if err := record("serviceaccount/"+sa.Name, err); err != nil {
+			return deleted, err
+		}
+	}
+
+	// The namespace is deleted last and only if it was created for the
+	// agent, users of namespace mode may not be allowed to read it
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, opts.Namespace, metav1.GetOptions{})
+	if k8serror.IsNotFound(err) || k8serror.IsForbidden(err) {
+		return deleted, nil
+	}
+	if err != nil {
+		return deleted, err
+	}
+	if labels.SelectorFromSet(OwnerLabels(opts.Owner)).Matches(labels.Set(ns.Labels)) {
+		err := clientset.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{})
+		if err := record("namespace/"+ns.Name, err); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// Provision calls Client.Provision on the default client
+func Provision(ctx context.Context, opts ProvisionOptions) ([]string, error) {
+	return Default().Provision(ctx, opts)
+}
+
+// Teardown calls Client.Teardown on the default client
+func Teardown(ctx context.Context, opts ProvisionOptions) ([]string, error) {
+	return Default().Teardown(ctx, opts)
+}
@@ -0,0 +1,242 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultReadyTimeout is the time given to the agent to become ready
+const DefaultReadyTimeout = 5 * time.Minute
+
+// readyPollInterval is the interval at which the readiness is checked
+const readyPollInterval = 2 * time.Second
+
+// ContainerFailure describes a container which isn't able to run
+type ContainerFailure struct {
+	Pod          string
+	Container    string
+	Reason       string
+	Message      string
+	RestartCount int32
+}
+
+func (f ContainerFailure) String() string {
+	msg := fmt.Sprintf("%s/%s: %s", f.Pod, f.Container, f.Reason)
+	if f.Message != "" {
+		msg += " (" + f.Message + ")"
+	}
+	if f.RestartCount > 0 {
+		msg += fmt.Sprintf(", restarted %d times", f.RestartCount)
+	}
+	return msg
+}
+
+// ReadinessError is returned when the agent doesn't become ready,
+// it holds the failing containers and the related warning events
+type ReadinessError struct {
+	Namespace string
+	Selector  string
+	Failures  []ContainerFailure
+	Events    []string
+	Err       error
+}
+
+func (e *ReadinessError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pods with label %s in %s namespace are not ready: %v", e.Selector, e.Namespace, e.Err)
+	for _, f := range e.Failures {
+		b.WriteString("\n  - " + f.String())
+	}
+	for _, event := range e.Events {
+		b.WriteString("\n  - " + event)
+	}
+	return b.String()
+}
+
+func (e *ReadinessError) Unwrap() error {
+	return e.Err
+}
+
+// failureReasons are the waiting reasons of containers
+// which aren't going to start without intervention
+var failureReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// WaitForAgentReady waits until the deployments and the pods matching
+// the selector in the given namespace are available and their containers
+// are ready. The progress and the failing containers are written to out
+// as they are found, os.Stdout is used if nil. A *ReadinessError
+// wrapping ErrAgentNotReady is returned on timeout.
+func (c *Client) WaitForAgentReady(ctx context.Context, namespace, selector string, timeout time.Duration, out io.Writer) error {
+	clientset, err := c.ClientSet()
+	if err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fmt.Fprintln(out, "💡 Connecting agent to Kubera Enterprise.")
+	reported := map[string]bool{}
+	var failures []ContainerFailure
+	var names []string
+	var lastErr error
+	err = wait.PollImmediateUntil(readyPollInterval, func() (bool, error) {
+		deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		lastErr = nil
+
+		names = names[:0]
+		for _, d := range deployments.Items {
+			names = append(names, d.Name)
+		}
+		for _, p := range pods.Items {
+			names = append(names, p.Name)
+		}
+		failures = containerFailures(pods.Items)
+		for _, f := range failures {
+			if key := f.Pod + "/" + f.Container + "/" + f.Reason; !reported[key] {
+				reported[key] = true
+				fmt.Fprintln(out, "⚠️ ", f)
+			}
+		}
+		return deploymentsAvailable(deployments.Items) && podsReady(pods.Items), nil
+	}, ctx.Done())
+	if err == nil {
+		fmt.Fprintln(out, "🏃 Agents running!!")
+		return nil
+	}
+
+	readinessErr := &ReadinessError{
+		Namespace: namespace,
+		Selector:  selector,
+		Failures:  failures,
+		Err:       ErrAgentNotReady,
+	}
+	if lastErr != nil {
+		readinessErr.Err = fmt.Errorf("%w: %v", ErrAgentNotReady, lastErr)
+	}
+	// The context of the wait is done, a fresh one is used to fetch the events
+	readinessErr.Events = warningEvents(clientset, namespace, names)
+	return readinessErr
+}
+
+// deploymentsAvailable checks if all the replicas of the given
+// deployments are updated and available
+func deploymentsAvailable(deployments []appsv1.Deployment) bool {
+	for _, d := range deployments {
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		if d.Status.ObservedGeneration < d.Generation ||
+			d.Status.UpdatedReplicas < replicas ||
+			d.Status.AvailableReplicas < replicas {
+			return false
+		}
+	}
+	return true
+}
+
+// podsReady checks if there are pods and all of their containers are ready
+func podsReady(pods []v1.Pod) bool {
+	if len(pods) == 0 {
+		return false
+	}
+	for _, p := range pods {
+		if p.DeletionTimestamp != nil {
+			continue
+		}
+		if p.Status.Phase != v1.PodRunning {
+			return false
+		}
+		for _, cs := range p.Status.ContainerStatuses {
+			if !cs.Ready {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containerFailures returns the containers of the given
+// pods which are waiting for a reason that needs attention
+func containerFailures(pods []v1.Pod) []ContainerFailure {
+	var failures []ContainerFailure
+	for _, p := range pods {
+		statuses := append(append([]v1.ContainerStatus{}, p.Status.InitContainerStatuses...), p.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			if cs.State.Waiting == nil || !failureReasons[cs.State.Waiting.Reason] {
+				continue
+			}
+			failures = append(failures, ContainerFailure{
+				Pod:          p.Name,
+				Container:    cs.Name,
+				Reason:       cs.State.Waiting.Reason,
+				Message:      cs.State.Waiting.Message,
+				RestartCount: cs.RestartCount,
+			})
+		}
+	}
+	return failures
+}
+
+// warningEvents returns the warning events of the objects whose names
+// start with any of the given names, so that the events of the replica
+// sets of the deployments are included
+func warningEvents(clientset kubernetes.Interface, namespace string, names []string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "type=" + v1.EventTypeWarning,
+	})
+	if err != nil {
+		return nil
+	}
+	var warnings []string
+	for _, e := range events.Items {
+		for _, name := range names {
+			if strings.HasPrefix(e.InvolvedObject.Name, name) {
+				warnings = append(warnings, fmt.Sprintf("%s/%s: %s: %s",
+					strings.ToLower(e.InvolvedObject.Kind), e.InvolvedObject.Name, e.Reason, e.Message))
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// WaitForAgentReady calls Client.WaitForAgentReady on the default client
+func WaitForAgentReady(ctx context.Context, namespace, selector string, timeout time.Duration, out io.Writer) error {
+	return Default().WaitForAgentReady(ctx, namespace, selector, timeout, out)
+}
@@ -2,41 +2,47 @@ package k8s
 
 import (
 	"context"
-	"fmt"
-	"log"
 
+	"github.com/mayadata-io/cli-utils/pkg/common/prompt"
 	"github.com/mayadata-io/cli-utils/pkg/constants"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // SAExists checks if the given service account exists in the given namespace
-func SAExists(namespace, serviceaccount string) bool {
-	clientset, err := ClientSet()
+func (c *Client) SAExists(namespace, serviceaccount string) (bool, error) {
+	clientset, err := c.ClientSet()
 	if err != nil {
-		log.Fatal(err)
+		return false, err
 	}
-	msg := fmt.Sprintf("serviceaccounts \"%s\" not found", serviceaccount)
-	_, newErr := clientset.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), serviceaccount, metav1.GetOptions{})
-	if newErr != nil {
-		if newErr.Error() == msg {
-			return false
-		}
-		log.Fatal(newErr)
+	_, err = clientset.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), serviceaccount, metav1.GetOptions{})
+	if k8serror.IsNotFound(err) {
+		return false, nil
 	}
-	return true
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // ValidSA gets a valid service account as input
-func ValidSA(namespace string) (string, bool) {
-	var sa string
-	fmt.Print("🔑 Enter service account [", constants.DefaultSA, "]: ")
-	fmt.Scanln(&sa)
-	if sa == "" {
-		sa = constants.DefaultSA
+func ValidSA(namespace string) (string, bool, error) {
+	p := prompt.Default()
+	sa, err := p.Input("🔑 Enter service account", constants.DefaultSA)
+	if err != nil {
+		return "", false, err
 	}
-	if SAExists(namespace, sa) {
-		fmt.Println("👍 Using the existing service account")
-		return sa, true
+	ok, err := SAExists(namespace, sa)
+	if err != nil {
+		return "", false, err
 	}
-	return sa, false
+	if ok {
+		p.Println("👍 Using the existing service account")
+	}
+	return sa, ok, nil
+}
+
+// SAExists calls Client.SAExists on the default client
+func SAExists(namespace, serviceaccount string) (bool, error) {
+	return Default().SAExists(namespace, serviceaccount)
 }
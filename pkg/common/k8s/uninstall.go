@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeleteSubscriber deletes the deployments matching the subscriber
+// label in the given namespace along with the config maps and secrets
+// used by the subscriber. The objects deleted are returned.
+func (c *Client) DeleteSubscriber(namespace, label string, configMaps, secrets []string) ([]string, error) {
+	clientset, err := c.ClientSet()
+	if err != nil {
+		return nil, err
+	}
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: label,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(deployments.Items) == 0 {
+		return nil, fmt.Errorf("%w in %s namespace", ErrSubscriberNotFound, namespace)
+	}
+
+	var deleted []string
+	// Pods of the deployments are deleted along with them
+	propagation := metav1.DeletePropagationForeground
+	for _, d := range deployments.Items {
+		err := clientset.AppsV1().Deployments(namespace).Delete(context.TODO(), d.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+		if err != nil && !k8serror.IsNotFound(err) {
+			return deleted, err
+		}
+		deleted = append(deleted, "deployment.apps/"+d.Name)
+	}
+	for _, name := range configMaps {
+		err := clientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if k8serror.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, "configmap/"+name)
+	}
+	for _, name := range secrets {
+		err := clientset.CoreV1().Secrets(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if k8serror.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, "secret/"+name)
+	}
+	return deleted, nil
+}
+
+// DeleteSubscriber calls Client.DeleteSubscriber on the default client
+func DeleteSubscriber(namespace, label string, configMaps, secrets []string) ([]string, error) {
+	return Default().DeleteSubscriber(namespace, label, configMaps, secrets)
+}
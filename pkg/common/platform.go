@@ -2,114 +2,378 @@ package common
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
 	"github.com/mayadata-io/cli-utils/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// discoverPlatform determines the host platform and returns it
-func DiscoverPlatform() string {
-	if ok, _ := IsAWSPlatform(); ok {
-		return "AWS"
+// Confidence of a platform detector in its result
+const (
+	ConfidenceNone    = 0
+	ConfidenceLow     = 40
+	ConfidenceMedium  = 70
+	ConfidenceHigh    = 90
+	ConfidenceCertain = 100
+)
+
+// ClusterInfo holds what the platform detectors look at. It's
+// fetched once and shared by all of them.
+type ClusterInfo struct {
+	// Nodes of the cluster, empty if they can't be listed
+	Nodes []corev1.Node
+	// APIGroups served by the cluster
+	APIGroups map[string]bool
+	// Version is the git version of the kubernetes server
+	Version string
+	// Client of the cluster, for detectors which need more details
+	Client *k8s.Client
+
+	nsMu       sync.Mutex
+	namespaces map[string]bool
+}
+
+// NsExists checks if the namespace exists, the result is kept
+// so that the detectors can call it without querying the cluster
+// every time
+func (info *ClusterInfo) NsExists(namespace string) bool {
+	info.nsMu.Lock()
+	defer info.nsMu.Unlock()
+	if exists, ok := info.namespaces[namespace]; ok {
+		return exists
 	}
-	if ok, _ := IsGKEPlatform(); ok {
-		return "GKE"
+	if info.namespaces == nil {
+		info.namespaces = map[string]bool{}
 	}
-	if ok, _ := IsOpenshiftPlatform(); ok {
-		return "Openshift"
+	exists, _ := info.Client.NsExists(namespace)
+	info.namespaces[namespace] = exists
+	return exists
+}
+
+// NodeProviderPrefix returns the number of nodes whose
+// provider ID starts with the given prefix
+func (info *ClusterInfo) NodeProviderPrefix(prefix string) int {
+	count := 0
+	for _, node := range info.Nodes {
+		if strings.HasPrefix(node.Spec.ProviderID, prefix) {
+			count++
+		}
 	}
-	if ok, _ := k8s.NsExists("cattle-system"); ok {
-		return "Rancher"
+	return count
+}
+
+// NodeLabel returns the number of nodes having the given label
+func (info *ClusterInfo) NodeLabel(label string) int {
+	count := 0
+	for _, node := range info.Nodes {
+		if _, ok := node.Labels[label]; ok {
+			count++
+		}
 	}
-	return constants.DefaultPlatform
+	return count
 }
 
-// IsAWSPlatform determines if the host platform is AWS
-// by checking the ProviderID inside node spec
-//
-// Sample node custom resource of an AWS node
-// {
-//     "apiVersion": "v1",
-//     "kind": "Node",
-//     ....
-//     "spec": {
-//         "providerID": "aws:///us-east-2b/i-0bf24d83f4b993738"
-//     }
-//   }
-// }
-func IsAWSPlatform() (bool, error) {
-	clientset, err := k8s.ClientSet()
-	if err != nil {
-		return false, err
+// PlatformDetector detects a platform and returns its confidence,
+// ConfidenceNone if the cluster doesn't run on the platform
+type PlatformDetector struct {
+	Name   string
+	Detect func(info *ClusterInfo) int
+}
+
+// PlatformMatch is a platform detected along with its confidence
+type PlatformMatch struct {
+	Name       string `json:"name"`
+	Confidence int    `json:"confidence"`
+}
+
+var (
+	detectorsMu       sync.Mutex
+	platformDetectors []PlatformDetector
+)
+
+// RegisterPlatform adds a detector to the registry, the platforms
+// are listed to the user in the order they are registered
+func RegisterPlatform(d PlatformDetector) {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	platformDetectors = append(platformDetectors, d)
+}
+
+func detectors() []PlatformDetector {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	return append([]PlatformDetector{}, platformDetectors...)
+}
+
+// Platforms returns the names of the registered platforms
+// followed by the default platform
+func Platforms() []string {
+	var names []string
+	for _, d := range detectors() {
+		names = append(names, d.Name)
 	}
-	nodeList, err := clientset.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{})
+	return append(names, constants.DefaultPlatform)
+}
+
+// PlatformList returns the numbered list of the platforms
+// shown to the user
+func PlatformList() string {
+	platforms := Platforms()
+	lines := make([]string, 0, len(platforms))
+	for i, name := range platforms {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetClusterInfo fetches the details of the cluster used by the
+// detectors. Nodes and API groups the user isn't allowed to read
+// are left empty, so that the detection goes on with what's known.
+func GetClusterInfo(c *k8s.Client) (*ClusterInfo, error) {
+	clientset, err := c.ClientSet()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if strings.HasPrefix(nodeList.Items[0].Spec.ProviderID, constants.AWSIdentifier) {
-		return true, nil
+	info := &ClusterInfo{APIGroups: map[string]bool{}, Client: c}
+	if nodeList, err := clientset.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{}); err == nil {
+		info.Nodes = nodeList.Items
 	}
-	return false, nil
+	if groups, err := clientset.Discovery().ServerGroups(); err == nil {
+		for _, g := range groups.Groups {
+			info.APIGroups[g.Name] = true
+		}
+	}
+	if version, err := clientset.Discovery().ServerVersion(); err == nil {
+		info.Version = version.GitVersion
+	}
+	return info, nil
 }
 
-// IsGKEPlatform determines if the host platform is GKE
-// by checking the ProviderID inside node spec
-//
-// Sample node custom resource of an GKE node
-// {
-//     "apiVersion": "v1",
-//     "kind": "Node",
-//     ....
-//     "spec": {
-//         "providerID": "gce://mayadata-demo-247709/us-central1-c/gke-kuberactl-default-pool-6017d869-62q5"
-//     }
-//   }
-// }
-func IsGKEPlatform() (bool, error) {
-	clientset, err := k8s.ClientSet()
+// clusterInfoKey is the key of the details of the cluster cached on a client
+const clusterInfoKey = "common.ClusterInfo"
+
+// cachedClusterInfo returns the details of the cluster of the client,
+// they're fetched by the first call for the client only and cached on it
+func cachedClusterInfo(c *k8s.Client) (*ClusterInfo, error) {
+	info, err := c.Cached(clusterInfoKey, func() (interface{}, error) {
+		return GetClusterInfo(c)
+	})
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	nodeList, err := clientset.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{})
+	return info.(*ClusterInfo), nil
+}
+
+// DetectPlatforms runs the registered detectors on the cluster of the
+// given client and returns the platforms detected, the most likely first.
+// The details of the cluster are fetched once per client.
+func DetectPlatforms(c *k8s.Client) ([]PlatformMatch, error) {
+	info, err := cachedClusterInfo(c)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if strings.HasPrefix(nodeList.Items[0].Spec.ProviderID, constants.GKEIdentifier) {
-		return true, nil
+	var matches []PlatformMatch
+	for _, d := range detectors() {
+		if confidence := d.Detect(info); confidence > ConfidenceNone {
+			matches = append(matches, PlatformMatch{Name: d.Name, Confidence: confidence})
+		}
 	}
-	return false, nil
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Confidence > matches[j].Confidence
+	})
+	return matches, nil
 }
 
-// IsOpenshiftPlatform determines if the host platform
-// is Openshift by checking "node.openshift.io/os_id"
-// label on the nodes
-//
-// Sample node custom resource of an Openshift node
-// {
-//     "apiVersion": "v1",
-//     "kind": "Node",
-//     "metadata": {
-//         "labels": {
-//             "node.openshift.io/os_id": "rhcos"
-//         }
-//    }
-//    ....
-// }
-func IsOpenshiftPlatform() (bool, error) {
-	clientset, err := k8s.ClientSet()
-	if err != nil {
-		return false, err
+// DiscoverPlatform determines the host platform and returns it
+func DiscoverPlatform() string {
+	return DiscoverClusterPlatform(k8s.Default())
+}
+
+// DiscoverClusterPlatform determines the platform of the
+// cluster of the given client and returns it
+func DiscoverClusterPlatform(c *k8s.Client) string {
+	matches, err := DetectPlatforms(c)
+	if err != nil || len(matches) == 0 {
+		return constants.DefaultPlatform
 	}
-	nodeList, err := clientset.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{
-		LabelSelector: constants.OpenshiftIdentifier,
-	})
+	return matches[0].Name
+}
+
+// isPlatform runs the detector of the given platform on the
+// cached details of the cluster
+func isPlatform(c *k8s.Client, name string) (bool, error) {
+	info, err := cachedClusterInfo(c)
 	if err != nil {
 		return false, err
 	}
-	if len(nodeList.Items) > 0 {
-		return true, nil
+	for _, d := range detectors() {
+		if d.Name == name {
+			return d.Detect(info) > ConfidenceNone, nil
+		}
 	}
 	return false, nil
 }
+
+func init() {
+	// Sample node custom resource of an AWS node
+	// {
+	//     "apiVersion": "v1",
+	//     "kind": "Node",
+	//     ....
+	//     "spec": {
+	//         "providerID": "aws:///us-east-2b/i-0bf24d83f4b993738"
+	//     }
+	// }
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformAWS,
+		Detect: func(info *ClusterInfo) int {
+			switch {
+			case info.NodeLabel("eks.amazonaws.com/nodegroup") > 0 || strings.Contains(info.Version, "-eks-"):
+				return ConfidenceCertain
+			case info.NodeProviderPrefix(constants.AWSIdentifier) > 0:
+				return ConfidenceHigh
+			}
+			return ConfidenceNone
+		},
+	})
+	// Sample node custom resource of an GKE node
+	// {
+	//     "apiVersion": "v1",
+	//     "kind": "Node",
+	//     ....
+	//     "spec": {
+	//         "providerID": "gce://mayadata-demo-247709/us-central1-c/gke-kuberactl-default-pool-6017d869-62q5"
+	//     }
+	// }
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformGKE,
+		Detect: func(info *ClusterInfo) int {
+			switch {
+			case info.NodeLabel("cloud.google.com/gke-nodepool") > 0 || strings.Contains(info.Version, "-gke."):
+				return ConfidenceCertain
+			case info.NodeProviderPrefix(constants.GKEIdentifier) > 0:
+				return ConfidenceHigh
+			}
+			return ConfidenceNone
+		},
+	})
+	// Openshift serves the config.openshift.io API group and labels
+	// its nodes with "node.openshift.io/os_id" e.g. rhcos
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformOpenshift,
+		Detect: func(info *ClusterInfo) int {
+			switch {
+			case info.APIGroups[constants.OpenshiftAPIGroup]:
+				return ConfidenceCertain
+			case info.NodeLabel(constants.OpenshiftIdentifier) > 0:
+				return ConfidenceHigh
+			}
+			return ConfidenceNone
+		},
+	})
+	// Rancher runs its agents in the cattle-system namespace
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformRancher,
+		Detect: func(info *ClusterInfo) int {
+			if info.APIGroups["management.cattle.io"] {
+				return ConfidenceMedium
+			}
+			if info.NsExists("cattle-system") {
+				return ConfidenceLow
+			}
+			return ConfidenceNone
+		},
+	})
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformAKS,
+		Detect: func(info *ClusterInfo) int {
+			switch {
+			case info.NodeLabel("kubernetes.azure.com/cluster") > 0:
+				return ConfidenceCertain
+			case info.NodeProviderPrefix(constants.AzureIdentifier) > 0:
+				return ConfidenceHigh
+			}
+			return ConfidenceNone
+		},
+	})
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformDigitalOcean,
+		Detect: func(info *ClusterInfo) int {
+			switch {
+			case info.NodeLabel("doks.digitalocean.com/node-id") > 0:
+				return ConfidenceCertain
+			case info.NodeProviderPrefix(constants.DigitalOceanIdentifier) > 0:
+				return ConfidenceHigh
+			}
+			return ConfidenceNone
+		},
+	})
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformK3s,
+		Detect: func(info *ClusterInfo) int {
+			switch {
+			case strings.Contains(info.Version, "+k3s"):
+				return ConfidenceCertain
+			case info.NodeProviderPrefix(constants.K3sIdentifier) > 0:
+				return ConfidenceHigh
+			}
+			return ConfidenceNone
+		},
+	})
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformKind,
+		Detect: func(info *ClusterInfo) int {
+			if info.NodeProviderPrefix(constants.KindIdentifier) > 0 {
+				return ConfidenceCertain
+			}
+			return ConfidenceNone
+		},
+	})
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformMinikube,
+		Detect: func(info *ClusterInfo) int {
+			if info.NodeLabel("minikube.k8s.io/name") > 0 {
+				return ConfidenceCertain
+			}
+			for _, node := range info.Nodes {
+				if node.Name == "minikube" {
+					return ConfidenceMedium
+				}
+			}
+			return ConfidenceNone
+		},
+	})
+	RegisterPlatform(PlatformDetector{
+		Name: constants.PlatformMicroK8s,
+		Detect: func(info *ClusterInfo) int {
+			if info.NodeLabel("microk8s.io/cluster") > 0 {
+				return ConfidenceCertain
+			}
+			return ConfidenceNone
+		},
+	})
+}
+
+// IsAWSPlatform determines if the host platform is AWS
+// by checking the ProviderID and the labels of the nodes
+func IsAWSPlatform() (bool, error) {
+	return isPlatform(k8s.Default(), constants.PlatformAWS)
+}
+
+// IsGKEPlatform determines if the host platform is GKE
+// by checking the ProviderID and the labels of the nodes
+func IsGKEPlatform() (bool, error) {
+	return isPlatform(k8s.Default(), constants.PlatformGKE)
+}
+
+// IsOpenshiftPlatform determines if the host platform is Openshift
+// by checking the config.openshift.io API group and the labels
+// of the nodes
+func IsOpenshiftPlatform() (bool, error) {
+	return isPlatform(k8s.Default(), constants.PlatformOpenshift)
+}
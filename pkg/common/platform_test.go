@@ -0,0 +1,25 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mayadata-io/cli-utils/pkg/constants"
+)
+
+func TestPlatformList(t *testing.T) {
+	platforms := Platforms()
+	if platforms[len(platforms)-1] != constants.DefaultPlatform {
+		t.Errorf("Platforms() = %v, want %s last", platforms, constants.DefaultPlatform)
+	}
+	lines := strings.Split(PlatformList(), "\n")
+	if len(lines) != len(platforms) {
+		t.Fatalf("PlatformList() has %d lines, want %d", len(lines), len(platforms))
+	}
+	for i, name := range platforms {
+		if want := fmt.Sprintf("%d. %s", i+1, name); lines[i] != want {
+			t.Errorf("line %d of PlatformList() = %q, want %q", i+1, lines[i], want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	ymlparser "gopkg.in/yaml.v2"
+)
+
+// Output formats supported by the printer
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// Printer renders a list of structs as a table, JSON or YAML.
+// The columns are named after the json tags of the struct fields.
+type Printer struct {
+	// Format is one of table, json or yaml, table is used if empty
+	Format string
+	// Columns to be printed, all the columns are printed if empty
+	Columns []string
+	// Out is where the list is printed, os.Stdout is used if nil
+	Out io.Writer
+}
+
+// column is a field of the struct being printed
+type column struct {
+	name  string
+	index int
+}
+
+// Print renders the given slice of structs
+func (p Printer) Print(list interface{}) error {
+	out := p.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("printer: expected a slice, got %T", list)
+	}
+	elem := v.Type().Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("printer: expected a slice of structs, got %T", list)
+	}
+	columns, err := p.columns(elem)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(p.Format) {
+	case "", OutputTable:
+		return printTable(out, v, columns)
+	case OutputJSON:
+		list := rows(v, columns)
+		jsonList := make([]jsonRow, len(list))
+		for i, row := range list {
+			jsonList[i] = jsonRow(row)
+		}
+		data, err := json.MarshalIndent(jsonList, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case OutputYAML:
+		data, err := ymlparser.Marshal(rows(v, columns))
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	default:
+		return fmt.Errorf("printer: unknown output format %q, must be one of %s, %s or %s", p.Format, OutputTable, OutputJSON, OutputYAML)
+	}
+}
+
+// columns returns the selected columns of the given struct type
+func (p Printer) columns(t reflect.Type) ([]column, error) {
+	var all []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		all = append(all, column{name: name, index: i})
+	}
+	if len(p.Columns) == 0 {
+		return all, nil
+	}
+
+	var selected []column
+	for _, name := range p.Columns {
+		found := false
+		for _, c := range all {
+			if strings.EqualFold(c.name, strings.TrimSpace(name)) {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("printer: unknown column %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// rows converts the list into key value pairs of the columns,
+// keeping the order of the columns
+func rows(v reflect.Value, columns []column) []ymlparser.MapSlice {
+	list := make([]ymlparser.MapSlice, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := reflect.Indirect(v.Index(i))
+		row := make(ymlparser.MapSlice, 0, len(columns))
+		for _, c := range columns {
+			row = append(row, ymlparser.MapItem{Key: c.name, Value: item.Field(c.index).Interface()})
+		}
+		list = append(list, row)
+	}
+	return list
+}
+
+// jsonRow is marshalled into a JSON object keeping the order of its keys
+type jsonRow ymlparser.MapSlice
+
+func (r jsonRow) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, item := range r {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(fmt.Sprint(item.Key))
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	return []byte(b.String()), nil
+}
+
+func printTable(out io.Writer, v reflect.Value, columns []column) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToUpper(c.name)
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for i := 0; i < v.Len(); i++ {
+		item := reflect.Indirect(v.Index(i))
+		values := make([]string, len(columns))
+		for j, c := range columns {
+			values[j] = fmt.Sprint(item.Field(c.index).Interface())
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
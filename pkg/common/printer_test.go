@@ -0,0 +1,116 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type printerItem struct {
+	Name    string `json:"name"`
+	Active  bool   `json:"is_active"`
+	Version string `json:"version,omitempty"`
+	Secret  string `json:"-"`
+	NoTag   int
+	hidden  string
+}
+
+var printerItems = []printerItem{
+	{Name: "agent-1", Active: true, Version: "1.9.0", Secret: "s1", NoTag: 1, hidden: "h"},
+	{Name: "agent-2", Version: "1.10.0", Secret: "s2", NoTag: 2},
+}
+
+func TestPrinterTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		want    string
+	}{
+		{
+			name: "all columns",
+			want: "NAME     IS_ACTIVE  VERSION  NOTAG\n" +
+				"agent-1  true       1.9.0    1\n" +
+				"agent-2  false      1.10.0   2\n",
+		},
+		{
+			name:    "chosen columns in the given order",
+			columns: []string{"version", "NAME"},
+			want: "VERSION  NAME\n" +
+				"1.9.0    agent-1\n" +
+				"1.10.0   agent-2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := (Printer{Columns: tt.columns, Out: &out}).Print(printerItems); err != nil {
+				t.Fatalf("Print() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Print() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrinterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		printer Printer
+		list    interface{}
+		wantErr string
+	}{
+		{name: "unknown column", printer: Printer{Columns: []string{"name", "secret"}}, list: printerItems, wantErr: `unknown column "secret"`},
+		{name: "unknown format", printer: Printer{Format: "xml"}, list: printerItems, wantErr: `unknown output format "xml"`},
+		{name: "not a slice", list: printerItems[0], wantErr: "expected a slice"},
+		{name: "not structs", list: []string{"a"}, wantErr: "expected a slice of structs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tt.printer.Out = &out
+			err := tt.printer.Print(tt.list)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Print() error = %v, want %q", err, tt.wantErr)
+			}
+			if out.Len() != 0 {
+				t.Errorf("Print() wrote %q on error", out.String())
+			}
+		})
+	}
+}
+
+func TestPrinterJSONKeepsColumnOrder(t *testing.T) {
+	var out bytes.Buffer
+	p := Printer{Format: OutputJSON, Columns: []string{"version", "name", "is_active"}, Out: &out}
+	if err := p.Print([]*printerItem{&printerItems[0]}); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	want := `[
+  {
+    "version": "1.9.0",
+    "name": "agent-1",
+    "is_active": true
+  }
+]
+`
+	if out.String() != want {
+		t.Errorf("Print() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPrinterYAML(t *testing.T) {
+	var out bytes.Buffer
+	p := Printer{Format: "YAML", Columns: []string{"name", "is_active"}, Out: &out}
+	if err := p.Print(printerItems); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	want := `- name: agent-1
+  is_active: true
+- name: agent-2
+  is_active: false
+`
+	if out.String() != want {
+		t.Errorf("Print() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -1,11 +1,5 @@
 package common
 
-import (
-	"fmt"
-
-	resty "github.com/go-resty/resty/v2"
-)
-
 type LaunchProductResponse struct {
 	Data LaunchProductData `json:"data"`
 }
@@ -13,26 +7,18 @@ type LaunchProductData struct {
 	LaunchProduct string `json:"launchProduct"`
 }
 
-// GetUserDetails fetches details of the input user
-func LaunchProduct(t Token, c Credentials, Product string) (LaunchProductResponse, error) {
+const launchProductQuery = `query launchProduct($type: ProductType!) {
+  launchProduct(type: $type)
+}`
+
+// LaunchProduct launches the given product for the user
+func LaunchProduct(s *Session, Product string) (LaunchProductResponse, error) {
 	var new LaunchProductResponse
-	client := resty.New()
-	bodyData := `{"query":"query{\n  launchProduct(type: ` + fmt.Sprintf("%s", Product) + `)\n}"}`
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("%s", t.AccessToken)).
-		SetHeader("Accept-Encoding", "gzip, deflate, br").
-		SetBody(bodyData).
-		// SetResult automatic unmarshalling for the request,
-		// if response status code is between 200 and 299
-		SetResult(&new).
-		Post(
-			fmt.Sprintf(
-				"%s/api/graphql/query",
-				c.Host,
-			),
-		)
-	if err != nil || !resp.IsSuccess() {
+	client := NewGraphQLClient(s, "")
+	err := client.Do(launchProductQuery, map[string]interface{}{
+		"type": Product,
+	}, &new.Data)
+	if err != nil {
 		return LaunchProductResponse{}, err
 	}
 
@@ -0,0 +1,314 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ErrNoAnswer is returned when the input ends before an answer is read
+var ErrNoAnswer = errors.New("no answer given")
+
+// Prompter asks the user for input
+type Prompter interface {
+	// Input asks for a line of text, def is returned for an empty answer
+	Input(message, def string) (string, error)
+	// Password asks for a secret without echoing it
+	Password(message string) ([]byte, error)
+	// Select lists the options and returns the index of the chosen one,
+	// def is returned for an empty answer and a negative def requires
+	// an answer
+	Select(message string, options []string, def int) (int, error)
+	// Confirm asks a yes or no question, def is returned for an empty answer
+	Confirm(message string, def bool) (bool, error)
+	// Println writes a message to the user
+	Println(a ...interface{})
+}
+
+// Terminal prompts on a terminal. All the answers are read through a
+// single buffered reader, so input typed ahead isn't lost between prompts.
+type Terminal struct {
+	mu  sync.Mutex
+	in  io.Reader
+	r   *bufio.Reader
+	out io.Writer
+}
+
+// NewTerminal returns a prompter reading from in and writing to out
+func NewTerminal(in io.Reader, out io.Writer) *Terminal {
+	return &Terminal{in: in, r: bufio.NewReader(in), out: out}
+}
+
+var (
+	defaultMu       sync.Mutex
+	defaultPrompter Prompter = NewTerminal(os.Stdin, os.Stdout)
+)
+
+// SetDefault sets the prompter used by the functions asking for input
+func SetDefault(p Prompter) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultPrompter = p
+}
+
+// Default returns the prompter used by the functions asking for input
+func Default() Prompter {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultPrompter
+}
+
+// readLine reads the next answer without the line ending, ErrNoAnswer
+// is returned if the input ends without one
+func (t *Terminal) readLine() (string, error) {
+	line, err := t.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err == io.EOF {
+		return "", ErrNoAnswer
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// ReadLine reads the next line without prompting
+func (t *Terminal) ReadLine() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.readLine()
+}
+
+// Input asks for a line of text
+func (t *Terminal) Input(message, def string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if def != "" {
+		fmt.Fprint(t.out, message, " [", def, "]: ")
+	} else {
+		fmt.Fprint(t.out, message, ": ")
+	}
+	answer, err := t.readLine()
+	if err != nil {
+		return "", err
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// Password asks for a secret, the echo is turned off if the
+// input is a terminal
+func (t *Terminal) Password(message string) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprint(t.out, message, ": ")
+	// Nothing typed ahead can be read once the reader has buffered it,
+	// so the terminal is read directly only if the buffer is empty
+	if f, ok := t.in.(*os.File); ok && t.r.Buffered() == 0 && terminal.IsTerminal(int(f.Fd())) {
+		pass, err := terminal.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(t.out)
+		return pass, err
+	}
+	answer, err := t.readLine()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(answer), nil
+}
+
+// Select lists the options numbered from 1 and asks for a number
+// or the name of an option
+func (t *Terminal) Select(message string, options []string, def int) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(options) == 0 {
+		return -1, errors.New("nothing to select from")
+	}
+	for i, option := range options {
+		fmt.Fprintf(t.out, "%d. %s\n", i+1, option)
+	}
+	for {
+		if def >= 0 && def < len(options) {
+			fmt.Fprint(t.out, message, " [", options[def], "]: ")
+		} else {
+			fmt.Fprint(t.out, message, ": ")
+		}
+		answer, err := t.readLine()
+		if err != nil {
+			return -1, err
+		}
+		if index, ok := parseSelection(strings.TrimSpace(answer), options, def); ok {
+			return index, nil
+		}
+		fmt.Fprintf(t.out, "🚫 Invalid choice. Please enter a number between 1 and %d\n", len(options))
+	}
+}
+
+// parseSelection returns the index of the option chosen by the answer
+func parseSelection(answer string, options []string, def int) (int, bool) {
+	if answer == "" {
+		return def, def >= 0 && def < len(options)
+	}
+	if n, err := strconv.Atoi(answer); err == nil {
+		return n - 1, n >= 1 && n <= len(options)
+	}
+	for i, option := range options {
+		if strings.EqualFold(option, answer) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Confirm asks a yes or no question
+func (t *Terminal) Confirm(message string, def bool) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	for {
+		fmt.Fprint(t.out, message, " ", hint, ": ")
+		answer, err := t.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(t.out, "🚫 Please answer yes or no")
+	}
+}
+
+// Println writes a message to the output of the terminal
+func (t *Terminal) Println(a ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintln(t.out, a...)
+}
+
+// Scripted answers the prompts with a fixed list of answers, one per
+// prompt. It's meant for tests and non-interactive runs. The prompts are
+// written to Out, they're discarded if it's nil.
+type Scripted struct {
+	Answers []string
+	Out     io.Writer
+
+	mu  sync.Mutex
+	pos int
+}
+
+// NewScripted returns a prompter giving the answers in order
+func NewScripted(answers ...string) *Scripted {
+	return &Scripted{Answers: answers}
+}
+
+// next returns the next answer after writing the prompt
+func (s *Scripted) next(prompt string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.Out
+	if out == nil {
+		out = ioutil.Discard
+	}
+	fmt.Fprint(out, prompt)
+	if s.pos >= len(s.Answers) {
+		return "", fmt.Errorf("%w for %q", ErrNoAnswer, strings.TrimSpace(prompt))
+	}
+	answer := s.Answers[s.pos]
+	s.pos++
+	fmt.Fprintln(out, answer)
+	return answer, nil
+}
+
+// Remaining returns the number of answers not used yet
+func (s *Scripted) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Answers) - s.pos
+}
+
+// ReadLine returns the next answer
+func (s *Scripted) ReadLine() (string, error) {
+	return s.next("")
+}
+
+// Input returns the next answer, def if it's empty
+func (s *Scripted) Input(message, def string) (string, error) {
+	answer, err := s.next(message + ": ")
+	if err != nil {
+		return "", err
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// Password returns the next answer
+func (s *Scripted) Password(message string) ([]byte, error) {
+	answer, err := s.next(message + ": ")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(answer), nil
+}
+
+// Select returns the option chosen by the next answer, given
+// as a number or the name of an option
+func (s *Scripted) Select(message string, options []string, def int) (int, error) {
+	answer, err := s.next(message + ": ")
+	if err != nil {
+		return -1, err
+	}
+	index, ok := parseSelection(strings.TrimSpace(answer), options, def)
+	if !ok {
+		return -1, fmt.Errorf("invalid choice %q for %q", answer, message)
+	}
+	return index, nil
+}
+
+// Confirm returns whether the next answer is yes
+func (s *Scripted) Confirm(message string, def bool) (bool, error) {
+	answer, err := s.next(message + ": ")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid answer %q for %q", answer, message)
+}
+
+// Println writes a message to Out
+func (s *Scripted) Println(a ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Out != nil {
+		fmt.Fprintln(s.Out, a...)
+	}
+}
@@ -0,0 +1,168 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSelection(t *testing.T) {
+	options := []string{"cluster", "namespace"}
+	tests := []struct {
+		name   string
+		answer string
+		def    int
+		want   int
+		wantOK bool
+	}{
+		{name: "number", answer: "1", def: -1, want: 0, wantOK: true},
+		{name: "last number", answer: "2", def: -1, want: 1, wantOK: true},
+		{name: "name", answer: "namespace", def: -1, want: 1, wantOK: true},
+		{name: "name in another case", answer: "Cluster", def: -1, want: 0, wantOK: true},
+		{name: "empty with default", answer: "", def: 1, want: 1, wantOK: true},
+		{name: "empty without default", answer: "", def: -1, wantOK: false},
+		{name: "empty with default out of range", answer: "", def: 2, wantOK: false},
+		{name: "zero", answer: "0", def: -1, wantOK: false},
+		{name: "number too large", answer: "3", def: -1, wantOK: false},
+		{name: "negative number", answer: "-1", def: -1, wantOK: false},
+		{name: "number followed by letters", answer: "123abc", def: 0, wantOK: false},
+		{name: "unknown name", answer: "nodes", def: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseSelection(tt.answer, options, tt.def)
+			if ok != tt.wantOK {
+				t.Fatalf("parseSelection(%q) ok = %v, want %v", tt.answer, ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parseSelection(%q) = %d, want %d", tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScripted(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []string
+		ask     func(p Prompter) (interface{}, error)
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:    "input",
+			answers: []string{" litmus "},
+			ask:     func(p Prompter) (interface{}, error) { return p.Input("namespace", "kubera") },
+			want:    "litmus",
+		},
+		{
+			name:    "input default",
+			answers: []string{""},
+			ask:     func(p Prompter) (interface{}, error) { return p.Input("namespace", "kubera") },
+			want:    "kubera",
+		},
+		{
+			name:    "select",
+			answers: []string{"2"},
+			ask:     func(p Prompter) (interface{}, error) { return p.Select("mode", []string{"cluster", "namespace"}, -1) },
+			want:    1,
+		},
+		{
+			name:    "select invalid",
+			answers: []string{"123abc"},
+			ask:     func(p Prompter) (interface{}, error) { return p.Select("mode", []string{"cluster", "namespace"}, 0) },
+			wantErr: true,
+		},
+		{
+			name:    "confirm yes",
+			answers: []string{"Yes"},
+			ask:     func(p Prompter) (interface{}, error) { return p.Confirm("continue?", false) },
+			want:    true,
+		},
+		{
+			name:    "confirm default",
+			answers: []string{""},
+			ask:     func(p Prompter) (interface{}, error) { return p.Confirm("continue?", true) },
+			want:    true,
+		},
+		{
+			name:    "confirm invalid",
+			answers: []string{"maybe"},
+			ask:     func(p Prompter) (interface{}, error) { return p.Confirm("continue?", false) },
+			wantErr: true,
+		},
+		{
+			name: "no answer left",
+			ask:  func(p Prompter) (interface{}, error) { return p.Input("namespace", "kubera") },
+			// Checked to be ErrNoAnswer below
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewScripted(tt.answers...)
+			got, err := tt.ask(p)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				if len(tt.answers) == 0 && !errors.Is(err, ErrNoAnswer) {
+					t.Errorf("error = %v, want %v", err, ErrNoAnswer)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if p.Remaining() != 0 {
+				t.Errorf("%d answers left", p.Remaining())
+			}
+		})
+	}
+}
+
+func TestTerminalSelectRetries(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(strings.NewReader("123abc\n0\nnamespace\n"), &out)
+	got, err := term.Select("mode", []string{"cluster", "namespace"}, -1)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Select() = %d, want 1", got)
+	}
+	if n := strings.Count(out.String(), "Invalid choice"); n != 2 {
+		t.Errorf("%d invalid choices reported, want 2:\n%s", n, out.String())
+	}
+}
+
+func TestTerminalConfirm(t *testing.T) {
+	tests := []struct {
+		input   string
+		def     bool
+		want    bool
+		wantErr error
+	}{
+		{input: "y\n", want: true},
+		{input: "no\n", def: true, want: false},
+		{input: "\n", def: true, want: true},
+		{input: "maybe\nyes\n", want: true},
+		{input: "", wantErr: ErrNoAnswer},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.input), func(t *testing.T) {
+			term := NewTerminal(strings.NewReader(tt.input), &bytes.Buffer{})
+			got, err := term.Confirm("continue?", tt.def)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Confirm() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Confirm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
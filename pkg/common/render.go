@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mayadata-io/cli-utils/pkg/common/k8s"
+	ymlparser "gopkg.in/yaml.v2"
+)
+
+// Kustomization is the kustomization.yaml listing the rendered resources
+type Kustomization struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Resources  []string `yaml:"resources"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// RenderManifest splits the given manifest into one file per
+// resource under dir and writes a kustomization.yaml listing them.
+// The paths of the files written are returned.
+func RenderManifest(manifest []byte, dir string) ([]string, error) {
+	objs, err := k8s.DecodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	kustomization := Kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+	}
+	var files []string
+	for i, obj := range objs {
+		data, err := ymlparser.Marshal(obj.Object)
+		if err != nil {
+			return files, err
+		}
+		// Files are prefixed with their position in the manifest
+		// so that they are listed in the order they are applied
+		name := fmt.Sprintf("%02d-%s-%s.yaml", i, obj.GetKind(), obj.GetName())
+		name = unsafeFileChars.ReplaceAllString(strings.ToLower(name), "-")
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return files, err
+		}
+		files = append(files, path)
+		kustomization.Resources = append(kustomization.Resources, name)
+	}
+
+	data, err := ymlparser.Marshal(kustomization)
+	if err != nil {
+		return files, err
+	}
+	path := filepath.Join(dir, "kustomization.yaml")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return files, err
+	}
+	return append(files, path), nil
+}
+
+// RenderYaml downloads the agent registration manifest and writes
+// it split into one file per resource under dir instead of applying it
+func RenderYaml(s *Session, token string, yamlPath string, dir string) ([]string, error) {
+	manifest, err := FetchManifest(s, token, yamlPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download registration yaml: %w", err)
+	}
+	return RenderManifest(manifest, dir)
+}
+
+// WriteYaml downloads the agent registration manifest and
+// writes it as a single stream to w instead of applying it
+func WriteYaml(s *Session, token string, yamlPath string, w io.Writer) error {
+	manifest, err := FetchManifest(s, token, yamlPath)
+	if err != nil {
+		return fmt.Errorf("failed to download registration yaml: %w", err)
+	}
+	// The manifest is validated before it's written
+	if _, err := k8s.DecodeManifest(manifest); err != nil {
+		return err
+	}
+	_, err = w.Write(manifest)
+	return err
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenExpiryDelta is the time before expiry at which
+// the token is refreshed
+const tokenExpiryDelta = 30 * time.Second
+
+// Session holds the credentials of the user along with the token
+// issued for them and refreshes the token when it expires
+type Session struct {
+	Credentials Credentials
+	Token       Token
+	// IssuedAt is the time at which the token was issued
+	IssuedAt time.Time
+
+	mu sync.Mutex
+}
+
+// NewSession logs in with the given credentials and
+// returns a session for them
+func NewSession(c Credentials) (*Session, error) {
+	t, err := Login(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		Credentials: c,
+		Token:       t,
+		IssuedAt:    time.Now(),
+	}, nil
+}
+
+// ExpiresAt returns the time at which the token expires, the zero
+// time is returned if the expiry of the token isn't known
+func (s *Session) ExpiresAt() time.Time {
+	if s.Token.ExpiresIn <= 0 || s.IssuedAt.IsZero() {
+		return time.Time{}
+	}
+	return s.IssuedAt.Add(time.Duration(s.Token.ExpiresIn) * time.Second)
+}
+
+// Expired checks if the token has expired or is about to expire
+func (s *Session) Expired() bool {
+	expiresAt := s.ExpiresAt()
+	if expiresAt.IsZero() {
+		return s.Token.AccessToken == ""
+	}
+	return time.Now().Add(tokenExpiryDelta).After(expiresAt)
+}
+
+// AccessToken returns a valid access token, the token is
+// refreshed if it has expired
+func (s *Session) AccessToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Expired() {
+		if err := s.refresh(); err != nil {
+			return "", err
+		}
+	}
+	return s.Token.AccessToken, nil
+}
+
+// Refresh fetches a new token for the credentials of the session
+func (s *Session) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refresh()
+}
+
+func (s *Session) refresh() error {
+	if len(s.Credentials.Password) == 0 {
+		return ErrSessionExpired
+	}
+	t, err := getToken(s.Credentials)
+	if err != nil {
+		return err
+	}
+	s.Token = t
+	s.IssuedAt = time.Now()
+	return nil
+}
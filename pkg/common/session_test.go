@@ -0,0 +1,90 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSessionExpired(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     Token
+		issuedAgo time.Duration
+		want      bool
+	}{
+		{name: "valid", token: Token{AccessToken: "t", ExpiresIn: 3600}},
+		{name: "expired", token: Token{AccessToken: "t", ExpiresIn: 60}, issuedAgo: 2 * time.Minute, want: true},
+		{name: "about to expire", token: Token{AccessToken: "t", ExpiresIn: 60}, issuedAgo: 40 * time.Second, want: true},
+		{name: "unknown expiry", token: Token{AccessToken: "t"}},
+		{name: "no token", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Session{Token: tt.token, IssuedAt: time.Now().Add(-tt.issuedAgo)}
+			if got := s.Expired(); got != tt.want {
+				t.Errorf("Expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionExpiresAt(t *testing.T) {
+	issued := time.Date(2021, time.March, 5, 12, 0, 0, 0, time.UTC)
+	s := &Session{Token: Token{AccessToken: "t", ExpiresIn: 600}, IssuedAt: issued}
+	if got := s.ExpiresAt(); !got.Equal(issued.Add(10 * time.Minute)) {
+		t.Errorf("ExpiresAt() = %v, want %v", got, issued.Add(10*time.Minute))
+	}
+	if got := (&Session{Token: Token{AccessToken: "t"}}).ExpiresAt(); !got.IsZero() {
+		t.Errorf("ExpiresAt() = %v without expiry, want the zero time", got)
+	}
+}
+
+func TestSessionAccessToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		issuedAt   time.Duration
+		want       string
+		wantErr    error
+		wantLogins int
+	}{
+		{name: "valid token", password: "secret", want: "current"},
+		{name: "expired token refreshed", password: "secret", issuedAt: -2 * time.Hour, want: "token-1", wantLogins: 1},
+		{name: "expired token without password", issuedAt: -2 * time.Hour, wantErr: ErrSessionExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portal := &fakePortal{handler: func(w http.ResponseWriter, r *http.Request) {}}
+			s := newTestSession(t, portal, "current", tt.password)
+			s.IssuedAt = s.IssuedAt.Add(tt.issuedAt)
+			got, err := s.AccessToken()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AccessToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AccessToken() = %q, want %q", got, tt.want)
+			}
+			if portal.logins != tt.wantLogins {
+				t.Errorf("%d logins, want %d", portal.logins, tt.wantLogins)
+			}
+			if tt.wantLogins > 0 && s.Expired() {
+				t.Error("session expired after refreshing the token")
+			}
+		})
+	}
+}
+
+func TestSessionRefreshFails(t *testing.T) {
+	portal := &fakePortal{}
+	s := newTestSession(t, portal, "current", "secret")
+	// The portal doesn't serve the token endpoint under this path
+	s.Credentials.Host.Path = "/missing"
+	if err := s.Refresh(); !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("Refresh() error = %v, want %v", err, ErrLoginFailed)
+	}
+	if s.Token.AccessToken != "current" {
+		t.Errorf("token = %q after a failed refresh, want it kept", s.Token.AccessToken)
+	}
+}
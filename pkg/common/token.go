@@ -3,9 +3,6 @@ package common
 import (
 	"fmt"
 	"net/url"
-	"os"
-
-	resty "github.com/go-resty/resty/v2"
 )
 
 type Cred struct {
@@ -31,10 +28,10 @@ type AuthError struct {
 }
 
 // getToken fetches JWT token for the entered user credentials
-func getToken(c Credentials) (Token, AuthError) {
+func getToken(c Credentials) (Token, error) {
 
 	var authErr AuthError
-	client := resty.New()
+	client := httpClient
 	token := Token{}
 	bodyData := map[string]interface{}{
 		"username": fmt.Sprintf("%s", c.Username),
@@ -54,20 +51,25 @@ func getToken(c Credentials) (Token, AuthError) {
 			),
 		)
 
-	if err != nil || !resp.IsSuccess() {
-		return Token{}, authErr
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+	if !resp.IsSuccess() || token.AccessToken == "" {
+		if authErr.Error == "" {
+			return Token{}, fmt.Errorf("%w: %s", ErrLoginFailed, resp.Status())
+		}
+		return Token{}, fmt.Errorf("%w: %s: %s", ErrLoginFailed, authErr.ErrorDescription, authErr.Error)
 	}
 
-	return token, AuthError{}
+	return token, nil
 }
 
-func Login(c Credentials) Token {
+// Login fetches the token for the given credentials
+func Login(c Credentials) (Token, error) {
 	t, err := getToken(c)
-	if err.Error != "" || t.AccessToken == "" {
-		fmt.Println("\nError: ", err.ErrorDescription, ":", err.Error)
-		fmt.Println("❌ Login Failed!!")
-		os.Exit(1)
+	if err != nil {
+		return Token{}, err
 	}
 	fmt.Println("\n✅ Login Successful!")
-	return t
+	return t, nil
 }
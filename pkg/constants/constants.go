@@ -12,6 +12,9 @@ const (
 	DefaultMode = "namespace"
 
 	// Platform list
+	//
+	// Deprecated: the list misses most of the supported platforms,
+	// use common.PlatformList which lists all the registered ones
 	PlatformList = "1. AWS\n2. GKE\n3. Openshift\n4. Rancher\n5. Others"
 
 	// AWS identifier
@@ -20,9 +23,24 @@ const (
 	// GKE identifier
 	GKEIdentifier = "gce://"
 
+	// AKS identifier
+	AzureIdentifier = "azure://"
+
+	// DigitalOcean identifier
+	DigitalOceanIdentifier = "digitalocean://"
+
+	// k3s identifier
+	K3sIdentifier = "k3s://"
+
+	// kind identifier
+	KindIdentifier = "kind://"
+
 	// Openshift identifier
 	OpenshiftIdentifier = "node.openshift.io/os_id"
 
+	// API group served by Openshift clusters
+	OpenshiftAPIGroup = "config.openshift.io"
+
 	// Default platform name
 	DefaultPlatform = "Others"
 
@@ -42,6 +60,41 @@ const (
 	ChaosYamlPath = "chaos/api/graphql/file"
 
 	ChaosAgentPath = "chaos/agents"
+
+	// Config map holding the configuration of the subscriber
+	ChaosAgentConfig = "agent-config"
+
+	// Secret holding the access key of the subscriber
+	ChaosAgentSecret = "agent-secret"
+
+	// Field manager used for server-side apply
+	FieldManager = "cli-utils"
+
+	// Label recording the tool which created an object
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+
+	// Label recording the agent an object was created for
+	OwnerLabel = "kubera.mayadata.io/agent"
+
+	// Name of the directory holding the config file
+	ConfigDirName = "kubera"
+
+	// Name of the config file
+	ConfigFileName = "config.yaml"
+)
+
+// Platform names
+const (
+	PlatformAWS          = "AWS"
+	PlatformGKE          = "GKE"
+	PlatformOpenshift    = "Openshift"
+	PlatformRancher      = "Rancher"
+	PlatformAKS          = "AKS"
+	PlatformDigitalOcean = "DigitalOcean"
+	PlatformK3s          = "k3s"
+	PlatformKind         = "kind"
+	PlatformMinikube     = "minikube"
+	PlatformMicroK8s     = "MicroK8s"
 )
 
 // Propel constants